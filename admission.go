@@ -0,0 +1,55 @@
+// Copyright 2025 Ahmad Sameh(asmsh)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sema
+
+// SetAdmissionGate sets the gate func that's consulted by every reserve call
+// that can report a failure, before it touches the counters, where a false
+// result rejects the call right away, regardless of the available room.
+// So [Group.ReserveN] returns false, [Group.ReserveNStatus] returns
+// [NotAdmitted], and the ctx-based methods return [ErrNotAdmitted], without
+// blocking.
+//
+// It lets an external circuit breaker, or a health signal, stop admitting
+// new reservations while it fails, and admit them again once it recovers,
+// while the reservations that are already made keep working, and are freed
+// as usual.
+// The calls that are already blocked aren't affected.
+//
+// The [Group.Reserve] calls can't report a rejection, so they're never gated,
+// and neither are the [Group.TryAdmit] calls, which bypass the [Group]'s
+// admission by design.
+//
+// The gate is called on each gated reserve call, so it should be cheap,
+// and safe to call concurrently.
+// A nil gate admits all calls, which is the default.
+func (g *Group) SetAdmissionGate(gate func() bool) {
+	e := g.initExt()
+	if gate == nil {
+		e.admissionGate.Store(nil)
+		return
+	}
+	e.admissionGate.Store(&gate)
+}
+
+// admitted reports whether a reserve call is admitted by the gate set via
+// [Group.SetAdmissionGate], if any.
+// It's called on a nil e too, which admits all calls.
+func (e *groupExt) admitted() bool {
+	if e == nil {
+		return true
+	}
+	gate := e.admissionGate.Load()
+	return gate == nil || (*gate)()
+}
@@ -0,0 +1,67 @@
+package sema_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/asmsh/sema"
+)
+
+func TestGroupSetAdmissionGate(t *testing.T) {
+	t.Parallel()
+
+	for _, size := range []int{0, 4} {
+		var healthy atomic.Bool
+		healthy.Store(true)
+
+		sg := sema.NewGroup(size)
+		sg.SetAdmissionGate(healthy.Load)
+
+		r, ok := sg.Acquire(nil, 2)
+		if !ok {
+			t.Fatalf("size %d: Acquire should succeed while admitted", size)
+		}
+
+		// flipping the gate stops the admission, regardless of the room.
+		healthy.Store(false)
+		if sg.ReserveN(nil, 1) {
+			t.Fatalf("size %d: ReserveN should fail while not admitted", size)
+		}
+		if sg.TryReserveN(1) {
+			t.Fatalf("size %d: TryReserveN should fail while not admitted", size)
+		}
+		if outcome := sg.ReserveNStatus(nil, 1); outcome != sema.NotAdmitted {
+			t.Fatalf("size %d: ReserveNStatus should be %v, got %v", size, sema.NotAdmitted, outcome)
+		}
+		if err := sg.Spawn(context.Background(), func() {}); !errors.Is(err, sema.ErrNotAdmitted) {
+			t.Fatalf("size %d: Spawn should fail with %v, got %v", size, sema.ErrNotAdmitted, err)
+		}
+		if active, pending := sg.ActiveCount(), sg.PendingCount(); active != 2 || pending != 0 {
+			t.Fatalf("size %d: Group counts should be 2 and 0, got %d and %d", size, active, pending)
+		}
+
+		// the existing reservations keep working, and Reserve isn't gated.
+		sg.Reserve()
+		sg.Free()
+		r.Release()
+		sg.Wait()
+
+		// flipping it back resumes the admission.
+		healthy.Store(true)
+		if !sg.TryReserveN(1) {
+			t.Fatalf("size %d: TryReserveN should succeed once admitted", size)
+		}
+		sg.Free()
+
+		// a nil gate admits all calls.
+		healthy.Store(false)
+		sg.SetAdmissionGate(nil)
+		if !sg.ReserveN(nil, 1) {
+			t.Fatalf("size %d: ReserveN should succeed with a nil gate", size)
+		}
+		sg.Free()
+		sg.Wait()
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2025 Ahmad Sameh(asmsh)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sema
+
+import (
+	"sync"
+	"time"
+)
+
+// Batch is a reservation that's held open for a window of time, to attach
+// a burst of work to it via [Batch.Add], as returned by
+// [Group.ReserveBatched].
+type Batch struct {
+	g *Group
+	n int
+
+	// open is set until the window closes, and work is the number of the
+	// attached work that didn't call [Batch.Done] yet, both guarded by mu.
+	mu   sync.Mutex
+	open bool
+	work int
+}
+
+// ReserveBatched reserves n, blocking if needed, exactly like
+// [Group.ReserveN] with a nil doneChan, and returns a [Batch] that holds
+// the reserved n for the provided window.
+//
+// While the window is open, [Batch.Add] attaches more work to the held n,
+// amortizing the reservation across a burst of work.
+// The reserved n is freed once the window closes and all the attached work
+// is done, so it must not be freed manually.
+//
+// It returns false with a nil [Batch] if n is greater than the [Group.Size],
+// or if it's not admitted by the gate set via [Group.SetAdmissionGate].
+// It can't be aborted, so it always waits as usual, and it's never rejected
+// by [Group.SetMaxPendingWeight].
+//
+// It panics if n is less than or equal to 0, or greater than [math.MaxInt32].
+func (g *Group) ReserveBatched(n int, window time.Duration) (*Batch, bool) {
+	if !g.reserveNWait(nil, n) {
+		return nil, false
+	}
+
+	b := &Batch{g: g, n: n, open: true}
+
+	timerChan, _ := g.newTimer(window)
+	go func() {
+		<-timerChan
+		b.close()
+	}()
+
+	return b, true
+}
+
+// Add attaches a unit of work to the [Batch], which must call [Batch.Done]
+// once it's done, and returns true.
+//
+// It returns false if the window of the [Batch] is already closed, in which
+// case nothing is attached, and the work must reserve on its own.
+func (b *Batch) Add() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.open {
+		return false
+	}
+	b.work++
+	return true
+}
+
+// Done marks a unit of work attached via [Batch.Add] as done, and frees the
+// n held by the [Batch] if it's the last one and the window is closed.
+//
+// It panics if it's called more times than [Batch.Add] returned true.
+func (b *Batch) Done() {
+	b.mu.Lock()
+	if b.work == 0 {
+		b.mu.Unlock()
+		panic("sema.Batch: negative batch work counter")
+	}
+	b.work--
+	free := !b.open && b.work == 0
+	b.mu.Unlock()
+
+	if free {
+		b.g.FreeN(b.n)
+	}
+}
+
+// close closes the window of the [Batch], and frees its n if there's no
+// attached work that's not done.
+func (b *Batch) close() {
+	b.mu.Lock()
+	b.open = false
+	free := b.work == 0
+	b.mu.Unlock()
+
+	if free {
+		b.g.FreeN(b.n)
+	}
+}
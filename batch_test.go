@@ -0,0 +1,81 @@
+package sema_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/asmsh/sema"
+)
+
+func TestGroupFakeClockReserveBatched(t *testing.T) {
+	t.Parallel()
+
+	sg := sema.NewGroup(2)
+	fc := newFakeClock(sg)
+
+	b, ok := sg.ReserveBatched(2, time.Second)
+	if !ok {
+		t.Fatalf("ReserveBatched should succeed")
+	}
+
+	// the work attached within the window shares the held n.
+	for range 3 {
+		if !b.Add() {
+			t.Fatalf("Add should succeed while the window is open")
+		}
+	}
+	if active := sg.ActiveCount(); active != 2 {
+		t.Fatalf("Group active count should be 2, got %d", active)
+	}
+	b.Done()
+
+	fc.WaitTimers(1)
+	fc.Advance(time.Second)
+
+	// the window closed, so no more work is attached.
+	for b.Add() {
+		b.Done()
+		time.Sleep(time.Millisecond)
+	}
+
+	// the held n is only freed once all the attached work is done.
+	b.Done()
+	if active := sg.ActiveCount(); active != 2 {
+		t.Fatalf("Group active count should be 2 with attached work, got %d", active)
+	}
+	b.Done()
+	sg.Wait()
+
+	if b, ok := sg.ReserveBatched(3, time.Second); ok || b != nil {
+		t.Fatalf("ReserveBatched should fail for n greater than the size")
+	}
+}
+
+func TestGroupFakeClockReserveBatchedIdle(t *testing.T) {
+	t.Parallel()
+
+	sg := sema.NewGroup(1)
+	fc := newFakeClock(sg)
+
+	// a batch without any attached work is freed once its window closes.
+	if _, ok := sg.ReserveBatched(1, time.Second); !ok {
+		t.Fatalf("ReserveBatched should succeed")
+	}
+	fc.WaitTimers(1)
+	fc.Advance(time.Second)
+	sg.Wait()
+}
+
+func TestGroupReserveBatchedNotAdmitted(t *testing.T) {
+	t.Parallel()
+
+	sg := sema.NewGroup(2)
+	sg.SetAdmissionGate(func() bool { return false })
+
+	if b, ok := sg.ReserveBatched(1, time.Second); ok || b != nil {
+		t.Fatalf("ReserveBatched should fail if it's not admitted")
+	}
+	if active := sg.ActiveCount(); active != 0 {
+		t.Fatalf("Group active count should be 0, got %d", active)
+	}
+}
@@ -1,6 +1,6 @@
 module github.com/asmsh/sema/benchmarks
 
-go 1.23.0
+go 1.24
 
 require (
 	github.com/asmsh/sema v0.0.0
@@ -143,8 +143,8 @@ func BenchmarkWaitGroupAddDone(b *testing.B) {
 	}
 }
 
-func benchmarkSemaGroupAddDone(b *testing.B, localWork int) {
-	var sg sema.Group
+func benchmarkSemaGroupAddDone(b *testing.B, localWork int, size int) {
+	sg := sema.NewGroup(size)
 	b.ReportAllocs()
 	b.RunParallel(func(pb *testing.PB) {
 		foo := 0
@@ -165,6 +165,7 @@ func BenchmarkSemaGroupAddDone(b *testing.B) {
 		name            string
 		localWork       int
 		highParallelism bool
+		size            int
 	}{
 		{
 			name: "no work",
@@ -178,17 +179,30 @@ func BenchmarkSemaGroupAddDone(b *testing.B) {
 			localWork:       100,
 			highParallelism: true,
 		},
+		{
+			name: "bounded-no work",
+			size: boundedAddDoneSize,
+		},
+		{
+			name:      "bounded-with work",
+			localWork: 100,
+			size:      boundedAddDoneSize,
+		},
 	}
 	for _, bm := range benchmarks {
 		b.Run(bm.name, func(b *testing.B) {
 			if bm.highParallelism {
 				b.SetParallelism(highParallelismNum)
 			}
-			benchmarkSemaGroupAddDone(b, bm.localWork)
+			benchmarkSemaGroupAddDone(b, bm.localWork, bm.size)
 		})
 	}
 }
 
+// boundedAddDoneSize is large enough for the AddDone benchmarks to never
+// block, so they measure the free path of a bounded Group without waiters.
+const boundedAddDoneSize = 1 << 20
+
 func benchmarkWaitGroupWait(b *testing.B, localWork int) {
 	var wg WaitGroup
 	b.ReportAllocs()
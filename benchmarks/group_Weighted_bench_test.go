@@ -12,7 +12,11 @@ package benchmarks_test
 import (
 	"context"
 	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"golang.org/x/sync/semaphore"
 
@@ -70,11 +74,7 @@ func newSemaGroup(n int64) semaGroup {
 }
 
 func (s semaGroup) Acquire(ctx context.Context, n int64) error {
-	if s.g.ReserveN(ctx.Done(), int(n)) {
-		return nil
-	}
-
-	return ctx.Err()
+	return s.g.ReserveNContext(ctx, int(n))
 }
 
 func (s semaGroup) TryAcquire(n int64) bool {
@@ -171,3 +171,209 @@ func BenchmarkAcquireSeq(b *testing.B) {
 		}
 	}
 }
+
+// BenchmarkTryAcquireParallel calls TryAcquire(1) and Release(1) from many
+// goroutines on a semaphore with room for all of them, which stresses
+// the contended success path of TryAcquire.
+func BenchmarkTryAcquireParallel(b *testing.B) {
+	for _, cap := range []int64{1 << 20} {
+		for _, w := range []struct {
+			name string
+			w    weighted
+		}{
+			{"Weighted", semaphore.NewWeighted(cap)},
+			{"sema.Group", newSemaGroup(cap)},
+		} {
+			b.Run(fmt.Sprintf("%s-%d", w.name, cap), func(b *testing.B) {
+				b.ReportAllocs()
+				b.RunParallel(func(pb *testing.PB) {
+					for pb.Next() {
+						if !w.w.TryAcquire(1) {
+							b.Fatalf("TryAcquire(1) = false, want true")
+						}
+						w.w.Release(1)
+					}
+				})
+			})
+		}
+	}
+}
+
+// BenchmarkReserveNCancel compares the overhead of cancelling reserve calls
+// via a doneChan, which requires a context or a channel, against via a flag.
+func BenchmarkReserveNCancel(b *testing.B) {
+	for _, cap := range []int{1, 1 << 20} {
+		b.Run(fmt.Sprintf("doneChan-%d", cap), func(b *testing.B) {
+			sg := sema.NewGroup(cap)
+			b.ReportAllocs()
+			for b.Loop() {
+				ctx, cancel := context.WithCancel(context.Background())
+				if !sg.ReserveN(ctx.Done(), 1) {
+					b.Fatalf("ReserveN(1) = false, want true")
+				}
+				sg.FreeN(1)
+				cancel()
+			}
+		})
+		b.Run(fmt.Sprintf("flag-%d", cap), func(b *testing.B) {
+			sg := sema.NewGroup(cap)
+			b.ReportAllocs()
+			for b.Loop() {
+				var cancel atomic.Bool
+				if !sg.ReserveNFlag(&cancel, 1) {
+					b.Fatalf("ReserveNFlag(1) = false, want true")
+				}
+				sg.FreeN(1)
+				cancel.Store(true)
+			}
+		})
+	}
+}
+
+// BenchmarkFreeBurstWakeups frees size slots in a tight loop while size
+// reserve calls are blocked, and reports the blocked calls woken up per
+// free call, to measure the handoffs made by bursts of frees.
+func BenchmarkFreeBurstWakeups(b *testing.B) {
+	for _, size := range []int{1, 8, 64} {
+		b.Run(fmt.Sprintf("sema.Group-%d", size), func(b *testing.B) {
+			sg := sema.NewGroup(size)
+			b.ReportAllocs()
+			for b.Loop() {
+				sg.ReserveN(nil, size)
+
+				done := make(chan struct{}, size)
+				for range size {
+					go func() {
+						sg.Reserve()
+						done <- struct{}{}
+					}()
+				}
+				for sg.PendingCount() != size {
+					runtime.Gosched()
+				}
+
+				for range size {
+					sg.Free()
+				}
+				for range size {
+					<-done
+				}
+				sg.FreeN(size)
+			}
+
+			stats := sg.WakeupStats()
+			b.ReportMetric(float64(stats.FreesThatWokeWaiter)/float64(b.N*size), "wakeups/free")
+		})
+	}
+}
+
+// BenchmarkAcquireRelease compares the allocations of the handle-based
+// reserve calls, where the [sema.Handle] state is recycled after warmup,
+// while each [sema.Reservation] is allocated.
+func BenchmarkAcquireRelease(b *testing.B) {
+	b.Run("Reservation", func(b *testing.B) {
+		sg := sema.NewGroup(1)
+		b.ReportAllocs()
+		for b.Loop() {
+			r, _ := sg.Acquire(nil, 1)
+			r.Release()
+		}
+	})
+	b.Run("Handle", func(b *testing.B) {
+		sg := sema.NewGroup(1)
+		h, _ := sg.AcquireHandle(nil, 1)
+		h.Release()
+		b.ReportAllocs()
+		for b.Loop() {
+			h, _ := sg.AcquireHandle(nil, 1)
+			h.Release()
+		}
+	})
+}
+
+// BenchmarkMixedWorkloadStarvation runs a mixed workload of large and small
+// reservers on each wakeup mode, and reports the longest wait observed by
+// any single reserve call of each kind, in addition to the throughput.
+//
+// The modes are the default random wakeup of the sema.Group, the same with
+// the large reservers made preemptive via sema.Group.ReservePreempt, and
+// the FIFO wakeup of the semaphore.Weighted, as a reference.
+func BenchmarkMixedWorkloadStarvation(b *testing.B) {
+	const (
+		cap    = 16
+		large  = 12
+		larges = 2
+		smalls = 8
+	)
+
+	for _, m := range []struct {
+		name string
+		new  func() (acquire func(n int, large bool), release func(n int))
+	}{
+		{"Weighted-FIFO", func() (func(int, bool), func(int)) {
+			w := semaphore.NewWeighted(cap)
+			return func(n int, _ bool) { w.Acquire(context.Background(), int64(n)) },
+				func(n int) { w.Release(int64(n)) }
+		}},
+		{"sema.Group-random", func() (func(int, bool), func(int)) {
+			sg := sema.NewGroup(cap)
+			return func(n int, _ bool) { sg.ReserveN(nil, n) }, sg.FreeN
+		}},
+		{"sema.Group-preempt-large", func() (func(int, bool), func(int)) {
+			sg := sema.NewGroup(cap)
+			return func(n int, large bool) {
+				if large {
+					sg.ReservePreempt(nil, n)
+					return
+				}
+				sg.ReserveN(nil, n)
+			}, sg.FreeN
+		}},
+	} {
+		b.Run(m.name, func(b *testing.B) {
+			acquire, release := m.new()
+
+			// maxWait holds the longest wait of the small and the large
+			// reservers, in that order.
+			var ops atomic.Int64
+			var maxWait [2]atomic.Int64
+
+			var wg sync.WaitGroup
+			run := func(n int, isLarge bool) {
+				defer wg.Done()
+				class := 0
+				if isLarge {
+					class = 1
+				}
+				for ops.Add(1) <= int64(b.N) {
+					start := time.Now()
+					acquire(n, isLarge)
+					wait := int64(time.Since(start))
+					for cur := maxWait[class].Load(); wait > cur; cur = maxWait[class].Load() {
+						if maxWait[class].CompareAndSwap(cur, wait) {
+							break
+						}
+					}
+
+					runtime.Gosched()
+					release(n)
+				}
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for range larges {
+				wg.Add(1)
+				go run(large, true)
+			}
+			for range smalls {
+				wg.Add(1)
+				go run(1, false)
+			}
+			wg.Wait()
+
+			b.ReportMetric(float64(maxWait[0].Load()), "small-max-wait-ns")
+			b.ReportMetric(float64(maxWait[1].Load()), "large-max-wait-ns")
+		})
+	}
+}
@@ -14,6 +14,7 @@ package benchmarks
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -26,10 +27,13 @@ func TestGroupDoesntBlockIfTooBig(t *testing.T) {
 
 	const n = 2
 	sg := sema.NewGroup(n)
+	tooBig := make(chan error, 1)
 	{
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
-		go sg.ReserveN(ctx.Done(), n+1)
+		// sema.Group.ReserveN panics for an n greater than the size, so use
+		// the variant that reports it instead.
+		go func() { tooBig <- sg.ReserveNContext(ctx, n+1) }()
 	}
 
 	g, ctx := errgroup.WithContext(context.Background())
@@ -47,4 +51,7 @@ func TestGroupDoesntBlockIfTooBig(t *testing.T) {
 	if err := g.Wait(); err != nil {
 		t.Errorf("semaphore.NewWeighted(%v) failed to AcquireCtx(_, 1) with AcquireCtx(_, %v) pending", n, n+1)
 	}
+	if err := <-tooBig; !errors.Is(err, sema.ErrReserveTooLarge) {
+		t.Errorf("ReserveNContext(_, %v) should fail with %v, got %v", n+1, sema.ErrReserveTooLarge, err)
+	}
 }
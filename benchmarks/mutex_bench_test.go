@@ -0,0 +1,84 @@
+// Copyright 2025 Ahmad Sameh(asmsh)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package benchmarks
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/asmsh/sema"
+)
+
+type locker interface {
+	Lock()
+	Unlock()
+}
+
+type semaGroupLocker struct {
+	sg *sema.Group
+}
+
+func (l semaGroupLocker) Lock()   { l.sg.Reserve() }
+func (l semaGroupLocker) Unlock() { l.sg.Free() }
+
+func benchmarkLocker(b *testing.B, newLocker func() locker, contended bool) {
+	b.ReportAllocs()
+	if !contended {
+		b.RunParallel(func(pb *testing.PB) {
+			l := newLocker()
+			for pb.Next() {
+				l.Lock()
+				l.Unlock()
+			}
+		})
+		return
+	}
+
+	l := newLocker()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			l.Lock()
+			l.Unlock()
+		}
+	})
+}
+
+func BenchmarkMutex(b *testing.B) {
+	lockers := []struct {
+		name      string
+		newLocker func() locker
+	}{
+		{
+			name:      "sync.Mutex",
+			newLocker: func() locker { return &sync.Mutex{} },
+		},
+		{
+			name:      "sema.Mutex",
+			newLocker: func() locker { return &sema.Mutex{} },
+		},
+		{
+			name:      "sema.Group",
+			newLocker: func() locker { return semaGroupLocker{sg: sema.NewGroup(1)} },
+		},
+	}
+	for _, l := range lockers {
+		b.Run(l.name+"-Uncontended", func(b *testing.B) {
+			benchmarkLocker(b, l.newLocker, false)
+		})
+		b.Run(l.name+"-Contended", func(b *testing.B) {
+			benchmarkLocker(b, l.newLocker, true)
+		})
+	}
+}
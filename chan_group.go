@@ -0,0 +1,226 @@
+// Copyright 2025 Ahmad Sameh(asmsh)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sema
+
+import (
+	"sync"
+)
+
+// ChanGroup is a simpler alternative to a bounded [Group], implemented with
+// a buffered channel of tokens, one per reserved unit, which is created via
+// [NewGroupChan].
+//
+// It shares the reserve, free, and wait methods of a [Group], but its size
+// is fixed, and it doesn't support any of the other [Group] features.
+// A blocked reserve call is woken up by the Go runtime, which serves the
+// blocked channel sends in roughly FIFO order, making it more predictable,
+// but reserve calls of n greater than 1 take their tokens one at a time,
+// serialized with each other, while holding the tokens taken so far.
+type ChanGroup struct {
+	tokens chan struct{}
+
+	// multiLock serializes the reserve calls of n greater than 1, so they
+	// can't deadlock by holding parts of their n while waiting for the rest.
+	// It's a channel of 1, so acquiring it can be aborted, or tried.
+	multiLock chan struct{}
+
+	// active is the n reserved by the completed reserve calls, and waitChan
+	// is closed once it reaches zero, both guarded by mu.
+	// waitChan is created lazily, and it's reset once closed.
+	mu       sync.Mutex
+	active   int
+	waitChan chan struct{}
+}
+
+// NewGroupChan creates a new [ChanGroup] with the provided size.
+//
+// It panics if size is less than or equal to 0, as an unbounded group
+// can't be implemented with a channel of tokens.
+func NewGroupChan(size int) *ChanGroup {
+	if size <= 0 {
+		panic("sema.ChanGroup: invalid size value")
+	}
+	return &ChanGroup{
+		tokens:    make(chan struct{}, size),
+		multiLock: make(chan struct{}, 1),
+	}
+}
+
+// Size returns the fixed size of the [ChanGroup].
+func (g *ChanGroup) Size() int {
+	return cap(g.tokens)
+}
+
+// ActiveCount returns the number of the reserved tokens.
+func (g *ChanGroup) ActiveCount() int {
+	return len(g.tokens)
+}
+
+// Reserve reserves 1, blocking until there's room.
+func (g *ChanGroup) Reserve() {
+	g.tokens <- struct{}{}
+	g.add(1)
+}
+
+// ReserveN reserves n, blocking until there's room, exactly like
+// [Group.ReserveN].
+//
+// It returns false if the provided doneChan became receive-ready before
+// the n was reserved, in which case the tokens taken so far are freed.
+// It returns false right away if doneChan is already receive-ready, even if
+// there's room for n, exactly like [Group.ReserveN].
+//
+// It panics if n is less than or equal to 0, or greater than the
+// [ChanGroup.Size].
+func (g *ChanGroup) ReserveN(doneChan <-chan struct{}, n int) bool {
+	if n <= 0 {
+		panic("sema.ChanGroup: invalid reserve N value")
+	}
+
+	if n > cap(g.tokens) {
+		panic("sema.ChanGroup: reserve N exceeds group size")
+	}
+
+	// check doneChan first, as the selects below pick at random between it
+	// and a free token.
+	select {
+	case <-doneChan:
+		return false
+	default:
+	}
+
+	if n > 1 {
+		select {
+		case g.multiLock <- struct{}{}:
+		case <-doneChan:
+			return false
+		}
+		defer func() { <-g.multiLock }()
+	}
+
+	for i := range n {
+		select {
+		case g.tokens <- struct{}{}:
+		case <-doneChan:
+			g.putTokens(i)
+			return false
+		}
+	}
+
+	g.add(n)
+	return true
+}
+
+// TryReserveN tries to reserve n without blocking, and returns true if it
+// was successful.
+// For n greater than 1, it also fails if another reserve call of n greater
+// than 1 is in progress, as it would have to wait for it.
+//
+// It panics if n is less than or equal to 0, or greater than the
+// [ChanGroup.Size].
+func (g *ChanGroup) TryReserveN(n int) bool {
+	if n <= 0 {
+		panic("sema.ChanGroup: invalid reserve N value")
+	}
+
+	if n > cap(g.tokens) {
+		panic("sema.ChanGroup: reserve N exceeds group size")
+	}
+
+	if n > 1 {
+		select {
+		case g.multiLock <- struct{}{}:
+		default:
+			return false
+		}
+		defer func() { <-g.multiLock }()
+	}
+
+	for i := range n {
+		select {
+		case g.tokens <- struct{}{}:
+		default:
+			g.putTokens(i)
+			return false
+		}
+	}
+
+	g.add(n)
+	return true
+}
+
+// Free frees 1, exactly like [ChanGroup.FreeN] with 1.
+func (g *ChanGroup) Free() {
+	g.FreeN(1)
+}
+
+// FreeN frees n, waking up the blocked reserve calls that it has room for,
+// and the [ChanGroup.Wait] calls if no tokens are reserved anymore.
+//
+// It panics if n is less than or equal to 0, or greater than the reserved n.
+func (g *ChanGroup) FreeN(n int) {
+	if n <= 0 {
+		panic("sema.ChanGroup: invalid free N value")
+	}
+
+	// update the count first, so an over free panics before taking the
+	// tokens of the reserve calls that are in progress.
+	g.mu.Lock()
+	if n > g.active {
+		g.mu.Unlock()
+		panic("sema.ChanGroup: negative group counter")
+	}
+	g.active -= n
+	if g.active == 0 && g.waitChan != nil {
+		close(g.waitChan)
+		g.waitChan = nil
+	}
+	g.mu.Unlock()
+
+	g.putTokens(n)
+}
+
+// Wait blocks until no tokens are reserved by the completed reserve calls.
+func (g *ChanGroup) Wait() {
+	<-g.WaitChan()
+}
+
+// WaitChan returns a channel that will be closed once no tokens are
+// reserved by the completed reserve calls.
+func (g *ChanGroup) WaitChan() <-chan struct{} {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.active == 0 {
+		return closedChan
+	}
+	if g.waitChan == nil {
+		g.waitChan = make(chan struct{})
+	}
+	return g.waitChan
+}
+
+func (g *ChanGroup) add(n int) {
+	g.mu.Lock()
+	g.active += n
+	g.mu.Unlock()
+}
+
+// putTokens gives back n reserved tokens.
+func (g *ChanGroup) putTokens(n int) {
+	for range n {
+		<-g.tokens
+	}
+}
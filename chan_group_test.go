@@ -0,0 +1,232 @@
+package sema_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/asmsh/sema"
+)
+
+// boundedGroup is the method set shared by [sema.Group] and [sema.ChanGroup].
+type boundedGroup interface {
+	Size() int
+	ActiveCount() int
+	Reserve()
+	ReserveN(doneChan <-chan struct{}, n int) bool
+	TryReserveN(n int) bool
+	Free()
+	FreeN(n int)
+	Wait()
+	WaitChan() <-chan struct{}
+}
+
+func TestBoundedGroups(t *testing.T) {
+	t.Parallel()
+
+	groups := []struct {
+		name string
+		new  func(size int) boundedGroup
+	}{
+		{"Group", func(size int) boundedGroup { return sema.NewGroup(size) }},
+		{"ChanGroup", func(size int) boundedGroup { return sema.NewGroupChan(size) }},
+	}
+
+	for _, tg := range groups {
+		t.Run(tg.name, func(t *testing.T) {
+			t.Parallel()
+
+			t.Run("limit", func(t *testing.T) {
+				t.Parallel()
+				testBoundedGroupLimit(t, tg.new(3))
+			})
+			t.Run("abort", func(t *testing.T) {
+				t.Parallel()
+				testBoundedGroupAbort(t, tg.new(3))
+			})
+			t.Run("wait", func(t *testing.T) {
+				t.Parallel()
+				testBoundedGroupWait(t, tg.new(3))
+			})
+			t.Run("panic", func(t *testing.T) {
+				t.Parallel()
+				testBoundedGroupPanic(t, tg.new(3))
+			})
+		})
+	}
+}
+
+func testBoundedGroupLimit(t *testing.T, g boundedGroup) {
+	if size := g.Size(); size != 3 {
+		t.Fatalf("size should be 3, got %d", size)
+	}
+
+	g.Reserve()
+	if !g.ReserveN(nil, 2) {
+		t.Fatalf("ReserveN should succeed within the size")
+	}
+	if g.TryReserveN(1) {
+		t.Fatalf("TryReserveN should fail without room")
+	}
+	if active := g.ActiveCount(); active != 3 {
+		t.Fatalf("active count should be 3, got %d", active)
+	}
+
+	reserved := make(chan bool)
+	go func() {
+		reserved <- g.ReserveN(nil, 2)
+	}()
+
+	g.Free()
+	select {
+	case <-reserved:
+		t.Fatalf("ReserveN should block until there's room for all its n")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	g.FreeN(2)
+	if !<-reserved {
+		t.Fatalf("ReserveN should succeed once there's room")
+	}
+
+	g.FreeN(2)
+	if active := g.ActiveCount(); active != 0 {
+		t.Fatalf("active count should be 0, got %d", active)
+	}
+}
+
+func testBoundedGroupAbort(t *testing.T, g boundedGroup) {
+	g.ReserveN(nil, 2)
+
+	doneChan := make(chan struct{})
+	reserved := make(chan bool)
+	go func() {
+		reserved <- g.ReserveN(doneChan, 2)
+	}()
+
+	close(doneChan)
+	if <-reserved {
+		t.Fatalf("ReserveN should be aborted")
+	}
+
+	// the aborted call doesn't hold any of its n.
+	if !g.TryReserveN(1) {
+		t.Fatalf("TryReserveN should succeed after an aborted call")
+	}
+	g.FreeN(3)
+}
+
+func testBoundedGroupWait(t *testing.T, g boundedGroup) {
+	// a Wait on an idle group returns right away.
+	g.Wait()
+
+	wg := sync.WaitGroup{}
+	for range 10 {
+		g.Reserve()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			time.Sleep(time.Millisecond)
+			g.Free()
+		}()
+	}
+
+	waitChan := g.WaitChan()
+	g.Wait()
+	<-waitChan
+
+	if active := g.ActiveCount(); active != 0 {
+		t.Fatalf("active count should be 0 after Wait, got %d", active)
+	}
+	wg.Wait()
+}
+
+func testBoundedGroupPanic(t *testing.T, g boundedGroup) {
+	for name, f := range map[string]func(){
+		"over freeing":                 func() { g.Free() },
+		"ReserveN over the size":       func() { g.ReserveN(nil, 4) },
+		"TryReserveN over the size":    func() { g.TryReserveN(4) },
+		"ReserveN over the size, done": func() { g.ReserveN(make(chan struct{}), 4) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s should panic", name)
+				}
+			}()
+			f()
+		}()
+	}
+	if active := g.ActiveCount(); active != 0 {
+		t.Fatalf("active count should be 0, got %d", active)
+	}
+}
+
+func TestNewGroupChanPanic(t *testing.T) {
+	t.Parallel()
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("NewGroupChan should panic for size 0")
+		}
+	}()
+	sema.NewGroupChan(0)
+}
+
+func TestChanGroupMultiBlocked(t *testing.T) {
+	t.Parallel()
+
+	g := sema.NewGroupChan(3)
+	g.ReserveN(nil, 2)
+
+	// a reserve call of 2 blocks on the missing token, holding the lock of
+	// the calls of n greater than 1.
+	doneChan := make(chan struct{})
+	reserved := make(chan bool)
+	go func() {
+		reserved <- g.ReserveN(doneChan, 2)
+	}()
+	for g.ActiveCount() != 3 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// the other calls of 2 don't wait for it.
+	if g.TryReserveN(2) {
+		t.Fatalf("TryReserveN should fail")
+	}
+	closedChan := make(chan struct{})
+	close(closedChan)
+	if g.ReserveN(closedChan, 2) {
+		t.Fatalf("ReserveN should be aborted")
+	}
+
+	close(doneChan)
+	if <-reserved {
+		t.Fatalf("ReserveN should be aborted")
+	}
+
+	g.FreeN(2)
+	g.Wait()
+	if !g.TryReserveN(3) {
+		t.Fatalf("TryReserveN should succeed once all is freed")
+	}
+	g.FreeN(3)
+}
+
+func TestChanGroupReserveNDone(t *testing.T) {
+	t.Parallel()
+
+	g := sema.NewGroupChan(2)
+
+	// a done doneChan always fails, even with room for n.
+	doneChan := make(chan struct{})
+	close(doneChan)
+	for range 100 {
+		if g.ReserveN(doneChan, 1) || g.ReserveN(doneChan, 2) {
+			t.Fatalf("ReserveN should fail with a done doneChan")
+		}
+	}
+	if active := g.ActiveCount(); active != 0 {
+		t.Fatalf("active count should be 0, got %d", active)
+	}
+}
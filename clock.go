@@ -0,0 +1,61 @@
+// Copyright 2025 Ahmad Sameh(asmsh)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sema
+
+import (
+	"time"
+)
+
+// clock is the source of time of a [Group]'s time-based behaviors, which is
+// the real clock, unless it's replaced via setClock in tests.
+type clock struct {
+	now func() time.Time
+
+	// newTimer returns a channel that receives once d elapses, and a func
+	// that stops it, like [time.NewTimer].
+	newTimer func(d time.Duration) (c <-chan time.Time, stop func() bool)
+}
+
+var realClock = &clock{
+	now: time.Now,
+	newTimer: func(d time.Duration) (<-chan time.Time, func() bool) {
+		t := time.NewTimer(d)
+		return t.C, t.Stop
+	},
+}
+
+// setClock replaces the clock of the [Group], which must be done before
+// it's used, as it's not synchronized with the calls that use it.
+// A nil c restores the real clock.
+func (g *Group) setClock(c *clock) {
+	g.initExt().clock = c
+}
+
+// clock returns the clock of the [Group], which is the real clock, unless
+// it's replaced via setClock.
+func (g *Group) clock() *clock {
+	if e := g.ext.Load(); e != nil && e.clock != nil {
+		return e.clock
+	}
+	return realClock
+}
+
+func (g *Group) now() time.Time {
+	return g.clock().now()
+}
+
+func (g *Group) newTimer(d time.Duration) (<-chan time.Time, func() bool) {
+	return g.clock().newTimer(d)
+}
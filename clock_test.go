@@ -0,0 +1,244 @@
+package sema_test
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/asmsh/sema"
+)
+
+// fakeClock is a manually advanced clock, whose timers fire only when it's
+// advanced past their deadline.
+type fakeClock struct {
+	mu       sync.Mutex
+	now      time.Time
+	nowCalls int
+	timers   []*fakeTimer
+}
+
+type fakeTimer struct {
+	at time.Time
+	c  chan time.Time
+}
+
+func newFakeClock(g *sema.Group) *fakeClock {
+	fc := &fakeClock{now: time.Now()}
+	sema.SetClock(g, fc.Now, fc.NewTimer)
+	return fc
+}
+
+func (fc *fakeClock) Now() time.Time {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.nowCalls++
+	return fc.now
+}
+
+func (fc *fakeClock) NewTimer(d time.Duration) (<-chan time.Time, func() bool) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	t := &fakeTimer{at: fc.now.Add(d), c: make(chan time.Time, 1)}
+	fc.timers = append(fc.timers, t)
+
+	stop := func() bool {
+		fc.mu.Lock()
+		defer fc.mu.Unlock()
+		for i, ft := range fc.timers {
+			if ft == t {
+				fc.timers = append(fc.timers[:i], fc.timers[i+1:]...)
+				return true
+			}
+		}
+		return false
+	}
+	return t.c, stop
+}
+
+// Advance moves the clock forward by d, firing the timers that are due.
+func (fc *fakeClock) Advance(d time.Duration) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	fc.now = fc.now.Add(d)
+	pending := fc.timers[:0]
+	for _, t := range fc.timers {
+		if t.at.After(fc.now) {
+			pending = append(pending, t)
+			continue
+		}
+		t.c <- fc.now
+	}
+	fc.timers = pending
+}
+
+// WaitTimers blocks until n timers are waiting to fire.
+func (fc *fakeClock) WaitTimers(n int) {
+	fc.waitFor(func() bool { return len(fc.timers) >= n })
+}
+
+// WaitNoTimers blocks until no timers are waiting to fire.
+func (fc *fakeClock) WaitNoTimers() {
+	fc.waitFor(func() bool { return len(fc.timers) == 0 })
+}
+
+// WaitNowCalls blocks until the time was read n times.
+func (fc *fakeClock) WaitNowCalls(n int) {
+	fc.waitFor(func() bool { return fc.nowCalls >= n })
+}
+
+func (fc *fakeClock) waitFor(cond func() bool) {
+	for {
+		fc.mu.Lock()
+		ok := cond()
+		fc.mu.Unlock()
+		if ok {
+			return
+		}
+		runtime.Gosched()
+	}
+}
+
+func TestGroupFakeClockPacing(t *testing.T) {
+	t.Parallel()
+
+	sg := sema.NewGroup(3)
+	fc := newFakeClock(sg)
+	sg.SetMinInterval(time.Second)
+
+	// the first call isn't paced.
+	sg.Reserve()
+
+	reserved := make(chan struct{})
+	go func() {
+		sg.Reserve()
+		close(reserved)
+	}()
+
+	// the second call waits for the whole interval, and no less.
+	fc.WaitTimers(1)
+	fc.Advance(time.Second - time.Nanosecond)
+	select {
+	case <-reserved:
+		t.Fatalf("Reserve shouldn't be granted before the interval elapses")
+	default:
+	}
+	fc.Advance(time.Nanosecond)
+	<-reserved
+
+	// once the interval elapsed without any call, the next one isn't paced.
+	fc.Advance(time.Second)
+	if !sg.TryReserveN(1) {
+		t.Fatalf("TryReserveN should succeed once the interval elapsed")
+	}
+
+	sg.FreeN(3)
+}
+
+func TestGroupFakeClockPacingPast(t *testing.T) {
+	t.Parallel()
+
+	sg := sema.NewGroup(2)
+	fc := newFakeClock(sg)
+
+	// the pacing times are derived from the fake clock only, so a fake
+	// clock that's far from the wall time paces like any other.
+	fc.now = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	sg.SetMinInterval(time.Second)
+
+	if !sg.TryReserveN(1) {
+		t.Fatalf("TryReserveN shouldn't be paced on the first call")
+	}
+	if sg.TryReserveN(1) {
+		t.Fatalf("TryReserveN should fail before the interval elapses")
+	}
+	fc.Advance(time.Second)
+	if !sg.TryReserveN(1) {
+		t.Fatalf("TryReserveN should succeed once the interval elapsed")
+	}
+
+	sg.FreeN(2)
+}
+
+func TestGroupFakeClockSlowReserve(t *testing.T) {
+	t.Parallel()
+
+	sg := sema.NewGroup(1)
+	fc := newFakeClock(sg)
+
+	var waited time.Duration
+	sg.SetSlowReserveThreshold(time.Minute, func(w time.Duration, n int) {
+		waited = w
+	})
+
+	sg.Reserve()
+	reserved := make(chan struct{})
+	go func() {
+		sg.Reserve()
+		close(reserved)
+	}()
+	// the blocked call reads the time once it enters the slow path.
+	fc.WaitNowCalls(1)
+	fc.Advance(time.Hour)
+	sg.Free()
+	<-reserved
+
+	if waited != time.Hour {
+		t.Fatalf("the slow reservation should be logged with 1h, got: %v", waited)
+	}
+	sg.Free()
+}
+
+func TestGroupFakeClockWaitWithProgress(t *testing.T) {
+	t.Parallel()
+
+	sg := sema.NewGroup(0)
+	fc := newFakeClock(sg)
+
+	sg.ReserveN(nil, 2)
+
+	type progress struct{ active, pending int }
+	progresses := make(chan progress)
+	abort := make(chan bool)
+	zeroed := make(chan bool)
+	go func() {
+		zeroed <- sg.WaitWithProgress(func(active, pending int) bool {
+			progresses <- progress{active, pending}
+			return <-abort
+		})
+	}()
+
+	// onStuck is called on each interval while the Group isn't zero.
+	for range 2 {
+		fc.WaitTimers(1)
+		fc.Advance(time.Second)
+		if p := <-progresses; p != (progress{2, 0}) {
+			t.Fatalf("onStuck should be called with 2 active, got %+v", p)
+		}
+		abort <- false
+	}
+
+	// returning true aborts the wait.
+	fc.WaitTimers(1)
+	fc.Advance(time.Second)
+	<-progresses
+	abort <- true
+	if <-zeroed {
+		t.Fatalf("WaitWithProgress should return false once aborted")
+	}
+
+	// otherwise, it returns once the Group reaches zero.
+	go func() {
+		zeroed <- sg.WaitWithProgress(func(active, pending int) bool {
+			t.Errorf("onStuck shouldn't be called")
+			return true
+		})
+	}()
+	fc.WaitTimers(1)
+	sg.FreeN(2)
+	if !<-zeroed {
+		t.Fatalf("WaitWithProgress should return true once the Group is zero")
+	}
+}
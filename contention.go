@@ -0,0 +1,66 @@
+// Copyright 2025 Ahmad Sameh(asmsh)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sema
+
+// contentionWindow is the minimum number of reservations that
+// [Group.ContentionRatio] reports on, once they're made.
+const contentionWindow = 128
+
+// contentionSnapshot is a point in the reservation counts, which
+// [Group.ContentionRatio] measures its window from.
+type contentionSnapshot struct {
+	reserves uint64
+	blocked  uint64
+}
+
+// ContentionRatio returns the fraction, from 0 to 1, of the recent
+// reservations that had to block before they were granted, or aborted.
+//
+// The recent reservations are the ones made since the start of the previous
+// window, where a new window starts on a ContentionRatio call once at least
+// 128 reservations were made in the current one.
+// So it covers at least the last 128 reservations, if made, and it should be
+// called regularly, like by a circuit breaker that sheds the load once the
+// ratio exceeds a threshold.
+//
+// Only the reserve calls that can block, like [Group.ReserveN], are counted,
+// and only while the [Group.Size] is enforced and isn't 0.
+// The counting is opt-in, so it starts on the first ContentionRatio call,
+// or once any other opt-in feature of the [Group] is used, whichever is
+// first, and the reservations made before that aren't counted.
+// It returns 0 if no reservations were made yet.
+func (g *Group) ContentionRatio() float64 {
+	e := g.initExt()
+	e.contentionMu.Lock()
+	defer e.contentionMu.Unlock()
+
+	// load the blocked count first, so it can't exceed the reserves count,
+	// as the reserves count is incremented first.
+	cur := contentionSnapshot{blocked: e.blocked.Load()}
+	cur.reserves = e.reserves.Load()
+
+	// start a new window once the current one is full, measuring from
+	// the start of the current one, which becomes the previous one.
+	if cur.reserves-e.contentionCur.reserves >= contentionWindow {
+		e.contentionPrev = e.contentionCur
+		e.contentionCur = cur
+	}
+
+	total := cur.reserves - e.contentionPrev.reserves
+	if total == 0 {
+		return 0
+	}
+	return float64(cur.blocked-e.contentionPrev.blocked) / float64(total)
+}
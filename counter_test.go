@@ -0,0 +1,66 @@
+package sema
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCounterUpdate(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		pending      uint32
+		active       int32
+		pendingDelta int
+		activeDelta  int
+		wantPending  uint32
+		wantActive   int32
+	}{
+		{"reserve", 0, 0, 0, 1, 0, 1},
+		{"free", 0, 1, 0, -1, 0, 0},
+		{"queue", 2, 3, 1, 0, 3, 3},
+		{"grant", 3, 3, -2, 2, 1, 5},
+		{"abort", 3, 3, -3, 0, 0, 3},
+		{"reserve to max active", 7, math.MaxInt32 - 1, 0, 1, 7, math.MaxInt32},
+		{"reserve max N", 7, 0, 0, maxN, 7, math.MaxInt32},
+		{"free max N", 7, math.MaxInt32, 0, -maxN, 7, 0},
+		{"grant max N", maxN, 0, -maxN, maxN, 0, math.MaxInt32},
+		{"free below zero", 7, 5, 0, -maxN, 7, 5 - math.MaxInt32},
+		{"free from negative", 0, math.MinInt32 + 1, 0, -1, 0, math.MinInt32},
+		// the active count wraps on its own, without touching pending.
+		{"reserve past max active", 7, math.MaxInt32, 0, 1, 7, math.MinInt32},
+		{"free past min active", 0, math.MinInt32, 0, -1, 0, math.MaxInt32},
+		{"pending to max", math.MaxUint32 - 1, 1, 1, 0, math.MaxUint32, 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			g := &Group{}
+			old := uint64(tc.pending)<<32 | uint64(uint32(tc.active))
+			g.counter.Store(old)
+
+			newCounter, ok := g.counterUpdate(old, tc.pendingDelta, tc.activeDelta)
+			if !ok {
+				t.Fatalf("counterUpdate should succeed on an unchanged counter")
+			}
+			if newCounter != g.counter.Load() {
+				t.Fatalf("counterUpdate should return the stored counter")
+			}
+
+			pending, active := counterParts(newCounter)
+			if pending != tc.wantPending || active != tc.wantActive {
+				t.Fatalf("counter should be (%d, %d), got (%d, %d)",
+					tc.wantPending, tc.wantActive, pending, active)
+			}
+		})
+	}
+}
+
+func TestCounterUpdateLostCAS(t *testing.T) {
+	g := &Group{}
+	g.counter.Store(1)
+
+	if _, ok := g.counterUpdate(0, 0, 1); ok {
+		t.Fatalf("counterUpdate should fail on a changed counter")
+	}
+	if counter := g.counter.Load(); counter != 1 {
+		t.Fatalf("counterUpdate shouldn't change the counter on failure, got %d", counter)
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2025 Ahmad Sameh(asmsh)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package errgroup provides an [errgroup.Group] whose goroutines are limited
+// by a [sema.Group].
+//
+// It's a separate module, so the core sema module doesn't depend on
+// golang.org/x/sync.
+package errgroup
+
+import (
+	"context"
+
+	"github.com/asmsh/sema"
+	"golang.org/x/sync/errgroup"
+)
+
+// Group is an [errgroup.Group] whose goroutines started via [Group.Go] are
+// limited by a [sema.Group].
+type Group struct {
+	eg  *errgroup.Group
+	sg  *sema.Group
+	ctx context.Context
+
+	// cancel cancels the parent of the errgroup's context, which allows
+	// canceling it before freeing the reservation of a failed function.
+	cancel context.CancelCauseFunc
+}
+
+// WithGroup returns a new [Group], the [sema.Group] of the provided size that
+// limits it, and a derived context, exactly like [errgroup.WithContext].
+//
+// The derived context is canceled the first time a function passed to
+// [Group.Go] returns a non-nil error, or the first time [Group.Wait] returns,
+// whichever occurs first.
+// Once it's canceled, any [Group.Go] calls blocked on the [sema.Group] are
+// aborted.
+func WithGroup(ctx context.Context, size int) (*Group, *sema.Group, context.Context) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	eg, ctx := errgroup.WithContext(ctx)
+	sg := sema.NewGroup(size)
+	return &Group{eg: eg, sg: sg, ctx: ctx, cancel: cancel}, sg, ctx
+}
+
+// Go reserves 1 from the [sema.Group], blocking if needed, then calls the
+// provided function in a new goroutine, exactly like [errgroup.Group.Go],
+// and frees the reserved 1 once the function returns.
+//
+// If the derived context is canceled before the reservation is made, the
+// function isn't called, and the context's cause is recorded as the error of
+// the call, which is either the error of the failed function that canceled it,
+// or the error of the canceled parent context.
+//
+// If the reservation is refused by the [sema.Group] instead, such as by the
+// gate set via [sema.Group.SetAdmissionGate], the function isn't called,
+// and the refusal error, such as [sema.ErrNotAdmitted], is recorded as the
+// error of the call, which cancels the derived context, like a failed
+// function does.
+func (g *Group) Go(f func() error) {
+	if err := g.sg.ReserveNContext(g.ctx, 1); err != nil {
+		if g.ctx.Err() != nil {
+			// the cause is the error of the failed function, if it's the one
+			// that canceled the context.
+			err = context.Cause(g.ctx)
+		} else {
+			g.cancel(err)
+		}
+		g.eg.Go(func() error { return err })
+		return
+	}
+
+	g.eg.Go(func() error {
+		err := f()
+		if err != nil {
+			// cancel before freeing, so the blocked Go calls are aborted
+			// rather than granted the freed room.
+			g.cancel(err)
+		}
+		g.sg.Free()
+		return err
+	})
+}
+
+// Wait blocks until all function calls from the [Group.Go] method have
+// returned, then returns the first non-nil error (if any) from them,
+// exactly like [errgroup.Group.Wait].
+func (g *Group) Wait() error {
+	defer g.cancel(nil)
+	return g.eg.Wait()
+}
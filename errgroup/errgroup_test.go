@@ -0,0 +1,100 @@
+package errgroup_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/asmsh/sema"
+	"github.com/asmsh/sema/errgroup"
+)
+
+func TestWithGroup(t *testing.T) {
+	t.Parallel()
+
+	t.Run("the concurrency is bounded by the size", func(t *testing.T) {
+		size := 3
+		g, sg, _ := errgroup.WithGroup(context.Background(), size)
+
+		var mu sync.Mutex
+		running, maxRunning := 0, 0
+		for range 30 {
+			g.Go(func() error {
+				mu.Lock()
+				running++
+				maxRunning = max(maxRunning, running)
+				mu.Unlock()
+
+				time.Sleep(time.Millisecond)
+
+				mu.Lock()
+				running--
+				mu.Unlock()
+				return nil
+			})
+		}
+
+		if err := g.Wait(); err != nil {
+			t.Fatalf("Wait should succeed, got %v", err)
+		}
+		if maxRunning > size {
+			t.Errorf("running functions should be at most %d, got %d", size, maxRunning)
+		}
+		if active := sg.ActiveCount(); active != 0 {
+			t.Errorf("Group active count should be 0, got %d", active)
+		}
+	})
+
+	t.Run("the first error aborts the pending reservations", func(t *testing.T) {
+		g, sg, ctx := errgroup.WithGroup(context.Background(), 1)
+
+		errFirst := errors.New("first")
+		release := make(chan struct{})
+		g.Go(func() error {
+			<-release
+			return errFirst
+		})
+
+		// this blocks until the first function fails and frees its slot,
+		// by which time the ctx is canceled.
+		aborted := make(chan struct{})
+		go func() {
+			defer close(aborted)
+			g.Go(func() error {
+				t.Errorf("the function of an aborted reservation shouldn't be called")
+				return nil
+			})
+		}()
+		for sg.PendingCount() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+
+		close(release)
+		<-aborted
+
+		if err := g.Wait(); err != errFirst {
+			t.Errorf("Wait should fail with %v, got %v", errFirst, err)
+		}
+		if ctx.Err() == nil {
+			t.Errorf("the derived ctx should be canceled")
+		}
+	})
+	t.Run("a refused reservation is reported by Wait", func(t *testing.T) {
+		g, sg, ctx := errgroup.WithGroup(context.Background(), 1)
+		sg.SetAdmissionGate(func() bool { return false })
+
+		g.Go(func() error {
+			t.Errorf("the function of a refused reservation shouldn't be called")
+			return nil
+		})
+
+		if err := g.Wait(); !errors.Is(err, sema.ErrNotAdmitted) {
+			t.Errorf("Wait should fail with %v, got %v", sema.ErrNotAdmitted, err)
+		}
+		if ctx.Err() == nil {
+			t.Errorf("the derived ctx should be canceled")
+		}
+	})
+}
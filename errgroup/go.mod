@@ -0,0 +1,10 @@
+module github.com/asmsh/sema/errgroup
+
+go 1.23.0
+
+require (
+	github.com/asmsh/sema v0.0.0
+	golang.org/x/sync v0.15.0
+)
+
+replace github.com/asmsh/sema v0.0.0 => ./..
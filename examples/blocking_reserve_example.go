@@ -16,9 +16,12 @@ func main() {
 	defer cancel()
 
 	for i := 0; i < 10; i++ {
-		sg.ReserveN(ctx.Done(), 5)
+		b, ok := sg.ReserveBlock(ctx.Done(), 5)
+		if !ok {
+			break
+		}
 		go func(i int) {
-			defer sg.FreeN(5)
+			defer b.Free()
 
 			// Do some work...
 			log.Println("some work...")
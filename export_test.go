@@ -0,0 +1,29 @@
+package sema
+
+import (
+	"time"
+)
+
+// SetTestHookBeforeGrant sets the hook that's called by a woken up reserve
+// call right before it takes the room it found, and returns a func that
+// clears it.
+// It's not safe to use while other tests are running reserve calls.
+func SetTestHookBeforeGrant(f func()) (restore func()) {
+	testHookBeforeGrant = f
+	return func() { testHookBeforeGrant = nil }
+}
+
+// SetClock replaces the clock of g with the provided funcs, which must be
+// done before g is used.
+func SetClock(
+	g *Group,
+	now func() time.Time,
+	newTimer func(d time.Duration) (<-chan time.Time, func() bool),
+) {
+	g.setClock(&clock{now: now, newTimer: newTimer})
+}
+
+// OpRecordSeq returns the seq that orders r among the other records.
+func OpRecordSeq(r OpRecord) uint64 {
+	return r.seq
+}
@@ -15,10 +15,30 @@
 package sema
 
 import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
 	"runtime"
+	"strconv"
+	"sync"
 	"sync/atomic"
+	"time"
 )
 
+// ErrReserveTooLarge is returned when the requested N is greater than the
+// [Group.Size], so the reservation can never succeed.
+var ErrReserveTooLarge = errors.New("sema.Group: reserve N exceeds group size")
+
+// ErrMaxPendingWeight is returned when a reservation is rejected, as blocking
+// it would exceed the max [Group.PendingCount] set via
+// [Group.SetMaxPendingWeight].
+var ErrMaxPendingWeight = errors.New("sema.Group: max pending weight exceeded")
+
+// ErrNotAdmitted is returned when a reservation is rejected, as it wasn't
+// admitted by the gate set via [Group.SetAdmissionGate].
+var ErrNotAdmitted = errors.New("sema.Group: reservation not admitted")
+
 // Group guards concurrent access to a resource by providing methods to
 // control concurrency, observe usage counters, and wait for in-flight
 // operations to complete.
@@ -27,6 +47,18 @@ import (
 // comparable to a [sync.WaitGroup].
 //
 // Group is best suited for concurrent tasks of equal weight or cost.
+//
+// In the terminology of the Go memory model, like a [sync.WaitGroup],
+// a [Group.Free] or [Group.FreeN] call "synchronizes before" the return of
+// any [Group.Wait] call that it unblocks, and the receive from the
+// [Group.WaitChan] that it closes.
+// It also synchronizes before the return of any reserve call that gets the
+// room it freed.
+// The counter reads, like [Group.ActiveCount], are atomic loads, so a free
+// call also synchronizes before any read that observes its effect.
+// But observing a zero [Group.ActiveCount] says nothing about the reserve
+// calls made concurrently with the read, so it's only equivalent to a
+// [Group.Wait] call once no more reserve calls are made.
 type Group struct {
 	// waitChan is created lazily in Wait, only if it hasn't already,
 	// and there are some active calls.
@@ -41,8 +73,138 @@ type Group struct {
 	// high 32 bits are pending count, low 32 bits are active count.
 	counter atomic.Uint64
 
+	// single is set if the size is 1, where [Group.Reserve] and [Group.Free]
+	// take a single CAS fast path, like a [Mutex], if there are no blocked
+	// calls, which is the only case where the counter is 0 or 1.
+	single atomic.Bool
+
+	// resized is set once [Group.Resize] is called, after which the size
+	// isn't fixed, so an n greater than it is no longer a programming error.
+	resized atomic.Bool
+
 	// size is the maximum number of N that can be reserved.
 	size atomic.Uint32
+
+	// waiters is the number of goroutines blocked in [Group.Wait] calls,
+	// which is reported by [Group.WaiterCount].
+	waiters atomic.Int32
+
+	// name is set only via [NewNamedGroup], and is never changed.
+	name string
+
+	// ext holds the state of the opt-in features, and it's created lazily,
+	// once any of them is used, via initExt.
+	ext atomic.Pointer[groupExt]
+}
+
+// groupExt is the state of the opt-in features of a [Group], which is kept
+// out of the [Group] itself, so it stays small, and the calls that don't use
+// any of them only check that it's nil.
+type groupExt struct {
+	// preemptChan is received from only by the blocked [Group.ReservePreempt]
+	// calls, in addition to the blockChan, whose number is preempters,
+	// so they're woken up first.
+	preemptChan chan struct{}
+	preempters  atomic.Int32
+
+	// blockedPreempters is the number of the preemptive calls that are
+	// blocked, as reported by [Group.MaxBlockedPriority].
+	blockedPreempters atomic.Int32
+
+	// dryRun is set if the size isn't enforced, via [Group.SetEnforce].
+	dryRun           atomic.Bool
+	wouldHaveBlocked atomic.Uint64
+
+	// freesWithNoWaiter and freesThatWokeWaiter are reported by
+	// [Group.WakeupStats].
+	freesWithNoWaiter   atomic.Uint64
+	freesThatWokeWaiter atomic.Uint64
+
+	// reserves and blocked are the counts of the reservations that reached
+	// the size limit, and of those that blocked, which are reported by
+	// [Group.ContentionRatio], as the difference from the start of its
+	// previous window, contentionPrev, while contentionCur is the start
+	// of its current window.
+	reserves       atomic.Uint64
+	blocked        atomic.Uint64
+	contentionMu   sync.Mutex
+	contentionPrev contentionSnapshot
+	contentionCur  contentionSnapshot
+
+	// slowReserve is set via [Group.SetSlowReserveThreshold].
+	slowReserve atomic.Pointer[slowReserve]
+
+	// profiling is set via [Group.EnableProfiling].
+	profiling atomic.Bool
+
+	// reentrant holds the reservations made via [Group.ReserveReentrant],
+	// keyed by their tokens, and it's created lazily.
+	reentrantMu sync.Mutex
+	reentrant   map[any]*reentrantHold
+
+	// acquireTracking is set via [Group.EnableAcquireTracking], and tracked
+	// holds the unreleased reservations made via [Group.Acquire] while it's
+	// set, and it's created lazily.
+	acquireTracking atomic.Bool
+	trackedMu       sync.Mutex
+	tracked         map[*Reservation]struct{}
+
+	// holdTotal and holdCount are the total time that the released
+	// reservations made via [Group.Acquire] were held for, and their count,
+	// which are used by [Group.EstimatedWait].
+	holdTotal atomic.Int64
+	holdCount atomic.Int64
+
+	// opLog is set via [Group.EnableOpLog].
+	opLog atomic.Pointer[opLog]
+
+	// logger is set via [Group.SetLogger].
+	logger atomic.Pointer[slog.Logger]
+
+	// admissionGate is set via [Group.SetAdmissionGate].
+	admissionGate atomic.Pointer[func() bool]
+
+	// maxHold is set via [Group.SetMaxHoldDuration].
+	maxHold atomic.Pointer[maxHold]
+
+	// once holds the in-flight reservations made via [Group.ReserveOnce],
+	// keyed by their keys, and it's created lazily.
+	onceMu sync.Mutex
+	once   map[any]*onceCall
+
+	// do holds the in-flight init calls made via [Group.DoOnce], keyed by
+	// their keys, and it's created lazily.
+	doMu sync.Mutex
+	do   map[any]*doCall
+
+	// maxPendingWeight is set via [Group.SetMaxPendingWeight].
+	maxPendingWeight atomic.Int32
+
+	// minInterval is set via [Group.SetMinInterval], and nextGrant is
+	// the earliest time, relative to paceEpoch, that the next paced
+	// grant is allowed at.
+	// paceEpoch is read from the clock once pacing is first enabled.
+	minInterval atomic.Int64
+	nextGrant   atomic.Int64
+	paceEpoch   atomic.Pointer[time.Time]
+
+	// clock is set only in tests, via setClock.
+	clock *clock
+
+	// queueDepth is set only if queue depth tracking is enabled via
+	// [Group.EnableQueueDepthTracking].
+	queueDepth atomic.Pointer[queueDepthTracker]
+}
+
+// initExt returns the state of the opt-in features of the [Group], creating
+// it if it hasn't been created yet.
+func (g *Group) initExt() *groupExt {
+	if e := g.ext.Load(); e != nil {
+		return e
+	}
+
+	g.ext.CompareAndSwap(nil, &groupExt{preemptChan: make(chan struct{})})
+	return g.ext.Load()
 }
 
 // NewGroup creates a new [Group] with the provided size.
@@ -53,6 +215,30 @@ func NewGroup(size int) *Group {
 	return g
 }
 
+// NewNamedGroup creates a new [Group] with the provided name and size.
+// The name identifies the [Group] in the values of the panics that it raises
+// on misuse, which is useful when many groups are used together.
+func NewNamedGroup(name string, size int) *Group {
+	g := &Group{name: name}
+	g.setSize(size)
+	return g
+}
+
+// Name returns the name of the [Group], as passed to [NewNamedGroup].
+// It's empty if the [Group] wasn't created via [NewNamedGroup].
+func (g *Group) Name() string {
+	return g.name
+}
+
+// panic panics with the provided message, prefixed with the [Group] name,
+// if it has one.
+func (g *Group) panic(msg string) {
+	if g.name == "" {
+		panic("sema.Group: " + msg)
+	}
+	panic("sema.Group " + strconv.Quote(g.name) + ": " + msg)
+}
+
 func (g *Group) setSize(size int) {
 	// normalize negative size to 0.
 	if size < 0 {
@@ -63,13 +249,35 @@ func (g *Group) setSize(size int) {
 		// make sure the size isn't too big.
 		s := uint32(size)
 		if int(s) != size {
-			panic("sema.Group: incorrect group size")
+			g.panic("incorrect group size")
 		}
 
 		// save the size and create the block chan.
+		// a chan is stored in an atomic.Value without boxing, as it's
+		// pointer-shaped, so storing it doesn't allocate.
 		g.size.Store(s)
 		g.blockChan.Store(make(chan struct{}))
 	}
+
+	g.single.Store(size == 1)
+}
+
+// maxN is the maximum N that a single call can reserve or free, as the
+// active count is stored as an int32 in the low 32 bits of the counter.
+const maxN = math.MaxInt32
+
+// checkN panics if n isn't a valid N for the provided op, which is either
+// "reserve" or "free".
+// n can't be 0 or negative, as 0 isn't a valid resource, and negative
+// values are for the opposite op, and it must fit in the active count half
+// of the counter.
+func (g *Group) checkN(n int, op string) {
+	if n <= 0 {
+		g.panic("invalid group " + op + " N value")
+	}
+	if n > maxN {
+		g.panic("group " + op + " N value out of range")
+	}
 }
 
 func counterParts(counter uint64) (pending uint32, active int32) {
@@ -83,19 +291,20 @@ func (g *Group) counterUpdate(
 ) (newCounter uint64, ok bool) {
 	oldPending, oldActive := counterParts(oldCounter)
 
+	// the pending and active counts are independent fields, so an active
+	// count that wraps never carries into, or borrows from, the pending count.
 	newPending := oldPending + uint32(pendingDelta)
 	newActive := oldActive + int32(activeDelta)
 
-	// Check for overflow/underflow from low
-	if activeDelta > 0 && newActive < oldActive {
-		newPending += 1 // Carry
-	} else if activeDelta < 0 && newActive > oldActive {
-		newPending -= 1 // Borrow
-	}
-
 	newCounter = uint64(newPending)<<32 | uint64(uint32(newActive))
 
 	if g.counter.CompareAndSwap(oldCounter, newCounter) {
+		if e := g.ext.Load(); e != nil {
+			if qd := e.queueDepth.Load(); qd != nil && newPending != oldPending {
+				qd.sample()
+			}
+			g.logCounterUpdate(pendingDelta, activeDelta, newCounter)
+		}
 		return newCounter, true
 	}
 
@@ -109,6 +318,7 @@ func (g *Group) counterUpdate(
 // method or through the [NewGroup] function.
 // This means that it should be called once before any other methods, and
 // only on the zero value of a [Group].
+// The size of a live [Group] is changed via [Group.Resize] instead.
 //
 // If size is zero or negative, then the [Group] has no limits, and no
 // [Group.Reserve] or [Group.ReserveN] calls will block.
@@ -117,19 +327,140 @@ func (g *Group) counterUpdate(
 func (g *Group) SetSize(size int) {
 	// check if the Group has already started counting.
 	if g.counter.Load() != 0 {
-		panic("sema.Group: concurrent Reserve calls while initializing group")
+		g.panic("concurrent Reserve calls while initializing group")
 	}
 
 	// check if the Group is already initialized.
 	if g.blockChan.Load() != nil {
-		panic("sema.Group: group already initialized")
+		g.panic("group already initialized")
 	}
 
 	g.setSize(size)
 
 	// re-check if the Group has already started counting.
 	if g.counter.Load() != 0 {
-		panic("sema.Group: concurrent Reserve calls while initializing group")
+		g.panic("concurrent Reserve calls while initializing group")
+	}
+}
+
+// Resize changes the [Group.Size] of a live [Group] to newSize, which is
+// safe to call concurrently with any other method, unlike [Group.SetSize].
+//
+// Growing the size wakes up enough blocked calls to fill the new headroom,
+// each of which takes the room it needs, or passes it on.
+//
+// Shrinking the size never evicts the active reservations, so the
+// [Group.ActiveCount] can exceed the new size until enough of them are
+// freed, while the new reserve calls block until there's room for them
+// within the new size.
+// The blocked calls that are part of the [Group.PendingCount] stay blocked,
+// unless their n is greater than the new size, in which case they give up
+// the next time they're woken up, and are removed from the
+// [Group.PendingCount].
+//
+// Once it's called, the size is no longer fixed, so whether an n fits can
+// depend on a concurrent Resize, and a reserve call for an n greater than
+// the size isn't a programming error anymore.
+// So [Group.ReserveN] and [Group.TryReserveN] no longer panic for it, and
+// such a call fails the same way, whether it's blocked or new, as
+// [Group.ReserveN] returns false, [Group.ReserveNStatus] returns
+// [Impossible], and [Group.ReserveNContext] returns [ErrReserveTooLarge].
+//
+// The size is a single atomic value, next to the packed counter of the
+// [Group.ActiveCount] and [Group.PendingCount], so a reserve call that's
+// concurrent with Resize is checked against either the old or the new size,
+// as if it happened entirely before or after it.
+//
+// It panics if newSize is less than or equal to 0, or if the [Group.Size]
+// is 0, as the unlimited [Group] doesn't track the blocked calls.
+func (g *Group) Resize(newSize int) {
+	if newSize <= 0 {
+		g.panic("invalid group resize value")
+	}
+
+	s := uint32(newSize)
+	if int(s) != newSize || s > maxN {
+		g.panic("incorrect group size")
+	}
+
+	blockChan := g.loadBlockChan()
+	if blockChan == nil {
+		g.panic("resize of an unlimited group")
+	}
+
+	// mark the group as resized before the new size can be observed, so
+	// a reserve call checked against it never panics.
+	g.resized.Store(true)
+	oldSize := g.size.Swap(s)
+	g.single.Store(newSize == 1)
+	if s <= oldSize {
+		return
+	}
+
+	// wake up the blocked calls, one for each resource of the new headroom,
+	// as each of them takes at least 1, and stop once there are none left,
+	// or the headroom is taken.
+	for range s - oldSize {
+		pending, active := counterParts(g.notifyFree(blockChan))
+		if pending == 0 || int(active) >= newSize {
+			return
+		}
+	}
+}
+
+// SetEnforce sets whether the [Group.Size] is enforced, which it is by default.
+//
+// When it's not enforced, the [Group] counts reservations but doesn't limit
+// them, so all [Group.Reserve], [Group.ReserveN] and [Group.TryReserveN]
+// calls succeed right away, as if the [Group.Size] is 0.
+// But the calls that would have blocked or failed if it was enforced are
+// counted, and reported by [Group.WouldHaveBlockedCount].
+// This allows observing the effect of a limit before enforcing it.
+//
+// The [Group.ActiveCount] is tracked accurately either way, so [Group.Wait]
+// works the same, and the [Group.ActiveCount] can exceed the [Group.Size]
+// while it's not enforced.
+// Enforcing it again only affects the calls made after that, which block
+// until the [Group.ActiveCount] goes back within the [Group.Size].
+//
+// It has no effect if the [Group.Size] is 0.
+func (g *Group) SetEnforce(enforce bool) {
+	g.initExt().dryRun.Store(!enforce)
+}
+
+// WouldHaveBlockedCount is the total number of reserve calls that would have
+// blocked, or failed for [Group.TryReserveN] calls, while the [Group.Size]
+// wasn't enforced, via [Group.SetEnforce].
+func (g *Group) WouldHaveBlockedCount() int {
+	e := g.ext.Load()
+	if e == nil {
+		return 0
+	}
+	return int(e.wouldHaveBlocked.Load())
+}
+
+// enforced reports whether the [Group.Size] is enforced, via
+// [Group.SetEnforce].
+// It's called on a nil e too, where it's enforced.
+func (e *groupExt) enforced() bool {
+	return e == nil || !e.dryRun.Load()
+}
+
+func (g *Group) reserveDryRun(e *groupExt, size uint32, reserveN int) {
+	for {
+		counter := g.counter.Load()
+		pending, active := counterParts(counter)
+
+		if _, ok := g.counterUpdate(counter, 0, reserveN); !ok {
+			continue
+		}
+
+		// check the room exactly like tryReserve does.
+		if pending != 0 || int(active)+reserveN > int(size) {
+			e.wouldHaveBlocked.Add(1)
+		}
+
+		return
 	}
 }
 
@@ -159,6 +490,108 @@ func (g *Group) PendingCount() int {
 	return int(pending)
 }
 
+// RawCounter returns the [Group.PendingCount] and [Group.ActiveCount] read
+// together from a single atomic load of the counter, so they're consistent
+// with each other, like in [Group.Stats], but without building a [Stats].
+//
+// It's a point-in-time snapshot, with no synchronization guarantees beyond
+// the single atomic load, so the counts might change right after it returns.
+func (g *Group) RawCounter() (pending, active uint32) {
+	pending, a := counterParts(g.counter.Load())
+	return pending, uint32(a)
+}
+
+// SetMaxPendingWeight sets the max [Group.PendingCount], which is the total
+// N of the blocked reserve calls, rather than their number.
+//
+// Once set, a reserve call that would block, and whose n would make the
+// [Group.PendingCount] exceed weight, fails right away instead, without
+// waiting for its doneChan, and without blocking.
+// Only the calls that can be aborted, via a non-nil doneChan or the like,
+// are rejected, so [Group.Reserve], and [Group.ReserveN] with a nil doneChan,
+// always wait as usual.
+// [Group.ReserveNStatus] reports these calls as [Rejected].
+// This bounds the work queued behind the [Group] by its weight, which is
+// more meaningful than the number of calls when their n vary.
+//
+// A weight less than or equal to 0 removes the limit, which is the default.
+func (g *Group) SetMaxPendingWeight(weight int) {
+	g.initExt().maxPendingWeight.Store(int32(min(max(weight, 0), maxN)))
+}
+
+// Stats is a point-in-time snapshot of a [Group]'s size and counters.
+type Stats struct {
+	Size    int
+	Active  int
+	Pending int
+}
+
+// Stats returns the [Group.Size], [Group.ActiveCount] and [Group.PendingCount]
+// read together, so the counters are consistent with each other, unlike
+// calling each method separately.
+func (g *Group) Stats() Stats {
+	pending, active := counterParts(g.counter.Load())
+	return Stats{
+		Size:    int(g.size.Load()),
+		Active:  int(active),
+		Pending: int(pending),
+	}
+}
+
+// slowReserve is the config set via [Group.SetSlowReserveThreshold].
+type slowReserve struct {
+	threshold time.Duration
+	log       func(waited time.Duration, n int)
+}
+
+// SetSlowReserveThreshold sets a log func that's called once by any reserve
+// call that blocked for longer than d, when it's granted, with how long it
+// blocked, and its n.
+//
+// The time is only sampled by the reserve calls that block, so the calls
+// that are granted right away aren't affected.
+// The aborted calls aren't logged.
+//
+// A nil log disables it, which is the default.
+func (g *Group) SetSlowReserveThreshold(d time.Duration, log func(waited time.Duration, n int)) {
+	e := g.initExt()
+	if log == nil {
+		e.slowReserve.Store(nil)
+		return
+	}
+	e.slowReserve.Store(&slowReserve{threshold: d, log: log})
+}
+
+// WakeupStats counts how the free calls of a [Group] went, as returned by
+// [Group.WakeupStats].
+type WakeupStats struct {
+	// FreesWithNoWaiter is the number of free calls that found no blocked
+	// reserve call to wake up.
+	FreesWithNoWaiter uint64
+
+	// FreesThatWokeWaiter is the number of free calls that handed off to
+	// a blocked reserve call.
+	FreesThatWokeWaiter uint64
+}
+
+// WakeupStats returns the wakeup counters of the [Group], which tell how
+// often its free calls were useful handoffs to blocked reserve calls,
+// rather than finding no one to wake up.
+//
+// The free calls counted are the [Group.FreeN] calls, and the aborted
+// reserve calls, which pass the wakeup on, on a [Group] with a non-zero
+// [Group.Size].
+// The counting is opt-in, so it starts on the first WakeupStats call,
+// or once any other opt-in feature of the [Group] is used, whichever is
+// first, and the free calls made before that aren't counted.
+func (g *Group) WakeupStats() WakeupStats {
+	e := g.initExt()
+	return WakeupStats{
+		FreesWithNoWaiter:   e.freesWithNoWaiter.Load(),
+		FreesThatWokeWaiter: e.freesThatWokeWaiter.Load(),
+	}
+}
+
 // Reserve increments [Group.ActiveCount] by 1, blocking if needed until
 // there's room made available by [Group.Free] or [Group.FreeN] calls.
 //
@@ -175,7 +608,20 @@ func (g *Group) PendingCount() int {
 // It always updates the [Group.ActiveCount] and [Group.PendingCount] before
 // returning.
 func (g *Group) Reserve() {
-	g.ReserveN(nil, 1)
+	// fast path for a size of 1: free with no blocked calls.
+	if g.single.Load() && g.counter.CompareAndSwap(0, 1) {
+		if e := g.ext.Load(); e != nil {
+			g.logOp(OpReserve, 1, 1)
+			e.reserves.Add(1)
+			if e.pacing() {
+				g.pace(nil, nil)
+			}
+		}
+		return
+	}
+
+	// it can't report a rejection, so it's never gated.
+	g.reserveN(nil, 1, &reserveOpts{ungated: true})
 }
 
 // ReserveN increments [Group.ActiveCount] by n, blocking if needed, as long
@@ -202,220 +648,1338 @@ func (g *Group) Reserve() {
 // the provided n will not move to the [Group.ActiveCount], and will be
 // removed from the [Group.PendingCount] before returning.
 //
-// It panics if n is less than or equal to 0.
+// It returns false right away if the doneChan is non-nil and blocking would
+// exceed the max [Group.PendingCount] set via [Group.SetMaxPendingWeight],
+// or if it's not admitted by the gate set via [Group.SetAdmissionGate].
+//
+// It panics if n is less than or equal to 0, or greater than [math.MaxInt32].
+// It also panics if n is greater than a non-zero [Group.Size], as such a call
+// can never succeed, which is a programming error, so it fails loudly instead
+// of blocking on the doneChan forever.
+// [Group.ReserveNStatus] and [Group.ReserveNContext] report it instead.
+// Once the [Group] is resized via [Group.Resize], its size isn't fixed, so
+// it returns false instead, as described there.
 //
 // Note: The doneChan becomes receive-ready when it's closed or sent to.
 func (g *Group) ReserveN(doneChan <-chan struct{}, n int) (reserved bool) {
-	if n <= 0 {
-		// n can't be 0 or negative, as 0 isn't a valid resource,
-		// and for negative values, [Group.FreeN] should be used.
-		panic("sema.Group: invalid group reserve N value")
+	outcome := g.reserveN(doneChan, n, nil)
+	if outcome == Impossible && !g.resized.Load() {
+		g.panic("reserve N exceeds group size")
 	}
 
-	// return and don't update any counters if the provided doneChan
-	// is already closed.
-	if doneChan != nil {
-		select {
-		case <-doneChan:
-			return false
-		default:
+	return outcome.Granted()
+}
+
+// exceedsSize reports whether a reserve call of n can't succeed, as n is
+// greater than the enforced, non-zero [Group.Size].
+// It's a programming error only if the [Group] was never resized.
+func (g *Group) exceedsSize(n int) bool {
+	size := g.size.Load()
+	return size != 0 && g.ext.Load().enforced() && n > int(size)
+}
+
+// reserveNWait is [Group.ReserveN] without the panic for n greater than the
+// [Group.Size], for the methods that report it as a failure instead, where
+// such a call is destined to fail, so it waits for the doneChan, if it's
+// provided, and returns false.
+func (g *Group) reserveNWait(doneChan <-chan struct{}, n int) bool {
+	outcome := g.reserveN(doneChan, n, nil)
+	if outcome == Impossible && doneChan != nil {
+		<-doneChan
+	}
+
+	return outcome.Granted()
+}
+
+// ReserveNWithCleanup increments [Group.ActiveCount] by n exactly like
+// [Group.ReserveN], and calls the provided onAbort func if, and only if,
+// the reservation was aborted via the provided doneChan.
+//
+// The onAbort func is called after the n is removed from the
+// [Group.PendingCount], and before returning false.
+// It's not called if the reservation was made, nor if it failed without
+// a doneChan, because n is greater than the [Group.Size].
+//
+// It panics if n is less than or equal to 0, or greater than [math.MaxInt32].
+func (g *Group) ReserveNWithCleanup(doneChan <-chan struct{}, n int, onAbort func()) bool {
+	outcome := g.reserveN(doneChan, n, nil)
+
+	if outcome == Impossible && doneChan != nil {
+		<-doneChan
+		outcome = Aborted
+	}
+
+	if outcome == Aborted && onAbort != nil {
+		onAbort()
+	}
+
+	return outcome.Granted()
+}
+
+// ReserveNWatch increments [Group.ActiveCount] by n exactly like
+// [Group.ReserveN] with ctx.Done() as its doneChan, and calls the provided
+// onPos func with the estimated queue position of the call, each time it
+// changes, while it's blocked.
+//
+// The position is the sum of the N of the other blocked calls, which is
+// only an estimate, as blocked calls are woken up in random order, and it's
+// only updated when the call is woken up to re-check for room, so onPos is
+// called from the blocked goroutine without any extra polling.
+// The onPos func isn't called if the call doesn't block.
+//
+// It panics if n is less than or equal to 0, or greater than [math.MaxInt32].
+func (g *Group) ReserveNWatch(ctx context.Context, n int, onPos func(pos int)) bool {
+	doneChan := ctx.Done()
+	outcome := g.reserveN(doneChan, n, &reserveOpts{onPos: onPos, ctx: ctx})
+
+	if outcome == Impossible && doneChan != nil {
+		<-doneChan
+	}
+
+	return outcome.Granted()
+}
+
+// ReserveNIf increments [Group.ActiveCount] by n exactly like
+// [Group.ReserveN], but if the call blocks, it only takes the room it found
+// if the provided cond func still returns true.
+//
+// The cond func is called by the blocked call each time it finds enough room
+// and is about to be granted, and never while it's waiting for room.
+// If it returns false, the call is aborted exactly like when the doneChan
+// becomes receive-ready, and it returns false.
+// The cond func isn't called if the call doesn't block, as the caller can
+// check it right before the call.
+//
+// It panics if n is less than or equal to 0, greater than [math.MaxInt32],
+// or if cond is nil.
+func (g *Group) ReserveNIf(doneChan <-chan struct{}, n int, cond func() bool) bool {
+	if cond == nil {
+		g.panic("nil group reserve cond func")
+	}
+
+	outcome := g.reserveN(doneChan, n, &reserveOpts{cond: cond})
+
+	if outcome == Impossible && doneChan != nil {
+		<-doneChan
+	}
+
+	return outcome.Granted()
+}
+
+// flagPollInterval is the interval between the checks of the cancel flag
+// of a blocked [Group.ReserveNFlag] call.
+const flagPollInterval = time.Millisecond
+
+// ReserveNFlag increments [Group.ActiveCount] by n exactly like
+// [Group.ReserveN], but aborts once the provided cancel flag is set,
+// instead of when a doneChan becomes receive-ready.
+//
+// The flag is checked wherever [Group.ReserveN] checks its doneChan, and
+// every millisecond while the call is blocked, so it's aborted within about
+// a millisecond of setting the flag, without allocating a channel for it.
+//
+// On abort, it removes n from the [Group.PendingCount] exactly like
+// [Group.ReserveN] does, and returns false.
+// If n is greater than the [Group.Size], it returns false right away.
+//
+// It panics if n is less than or equal to 0, greater than [math.MaxInt32],
+// or if cancel is nil.
+func (g *Group) ReserveNFlag(cancel *atomic.Bool, n int) bool {
+	if cancel == nil {
+		g.panic("nil group reserve cancel flag")
+	}
+
+	return g.reserveN(nil, n, &reserveOpts{cancel: cancel}).Granted()
+}
+
+// ReserveNSaturating increments [Group.ActiveCount] by n exactly like
+// [Group.ReserveN], and also reports whether it's the call whose grant made
+// the [Group.ActiveCount] reach the [Group.Size].
+//
+// So a single call reports saturated as true per saturation of the [Group],
+// which lets exactly one caller react to it, like by requesting a scale up.
+// It's always false if the [Group.Size] is 0, or isn't enforced.
+//
+// It panics if n is less than or equal to 0, or greater than [math.MaxInt32].
+func (g *Group) ReserveNSaturating(doneChan <-chan struct{}, n int) (ok, saturated bool) {
+	opts := &reserveOpts{}
+	outcome := g.reserveN(doneChan, n, opts)
+
+	if outcome == Impossible && doneChan != nil {
+		<-doneChan
+	}
+
+	return outcome.Granted(), outcome.Granted() && opts.saturated
+}
+
+// ReservePreempt increments [Group.ActiveCount] by n exactly like
+// [Group.ReserveN], but while it's blocked, it's preferred over the blocked
+// calls that aren't preemptive.
+//
+// Each [Group.Free] or [Group.FreeN] call that finds blocked calls wakes up
+// a blocked preemptive call first, if there's any, which then takes the room
+// it needs, or passes it on like any woken up call.
+// This is a two-tier priority, where the preemptive calls are woken up in
+// random order among themselves, and a call that's not preemptive can still
+// take the room if it was already woken up to re-check for it.
+//
+// It panics if n is less than or equal to 0, or greater than [math.MaxInt32].
+func (g *Group) ReservePreempt(doneChan <-chan struct{}, n int) bool {
+	e := g.initExt()
+	e.preempters.Add(1)
+	defer e.preempters.Add(-1)
+
+	outcome := g.reserveN(doneChan, n, &reserveOpts{preempt: true})
+
+	if outcome == Impossible && doneChan != nil {
+		<-doneChan
+	}
+
+	return outcome.Granted()
+}
+
+// The priorities of the reserve calls, as reported by
+// [Group.MaxBlockedPriority].
+const (
+	// NoBlockedPriority means that no reserve call is blocked.
+	NoBlockedPriority = -1
+
+	// NormalPriority is the priority of the reserve calls that aren't
+	// preemptive.
+	NormalPriority = 0
+
+	// PreemptPriority is the priority of the [Group.ReservePreempt] calls.
+	PreemptPriority = 1
+)
+
+// MaxBlockedPriority returns the highest priority among the blocked reserve
+// calls, which is [PreemptPriority] if any [Group.ReservePreempt] call is
+// blocked, [NormalPriority] if only other calls are blocked, and
+// [NoBlockedPriority] if no call is blocked.
+//
+// A [PreemptPriority] result means that the room a preemptive call needs is
+// held by others, which is a priority inversion if it's held by work of
+// a lower priority, so a scheduler can detect it, and speed up that work,
+// or reclaim its room, like [Group.SetMaxHoldDuration] does.
+//
+// It's only a snapshot, which might be stale by the time it's returned.
+func (g *Group) MaxBlockedPriority() int {
+	if e := g.ext.Load(); e != nil && e.blockedPreempters.Load() > 0 {
+		return PreemptPriority
+	}
+	if pending, _ := counterParts(g.counter.Load()); pending > 0 {
+		return NormalPriority
+	}
+	return NoBlockedPriority
+}
+
+// ReserveNTimer increments [Group.ActiveCount] by n exactly like
+// [Group.ReserveN], but aborts once the provided timer fires, instead of
+// when a doneChan becomes receive-ready.
+//
+// On abort, it removes n from the [Group.PendingCount] exactly like
+// [Group.ReserveN] does, and returns false.
+// If n is greater than the [Group.Size], it waits for the timer to fire,
+// then returns false.
+//
+// It never stops or resets the timer, as it's owned by the caller.
+// But if it aborts, it has received the timer's value from its channel,
+// so the caller shouldn't receive from the channel after it returns false.
+//
+// It panics if n is less than or equal to 0, or greater than [math.MaxInt32],
+// or if timer is nil.
+func (g *Group) ReserveNTimer(timer *time.Timer, n int) bool {
+	if timer == nil {
+		g.panic("nil timer")
+	}
+
+	opts := &reserveOpts{timerChan: timer.C}
+	outcome := g.reserveN(nil, n, opts)
+
+	if outcome == Impossible {
+		<-opts.timerChan
+	}
+
+	return outcome.Granted()
+}
+
+// Outcome describes how a reserve call ended, as returned by
+// [Group.ReserveNStatus].
+type Outcome uint8
+
+const (
+	// GrantedImmediately means that the reservation was made without blocking.
+	GrantedImmediately Outcome = iota
+
+	// GrantedAfterWait means that the reservation was made after blocking
+	// until there was room for it.
+	GrantedAfterWait
+
+	// Aborted means that the reservation wasn't made, as it was aborted via
+	// the provided doneChan.
+	Aborted
+
+	// Impossible means that the reservation wasn't made, as the requested N
+	// is greater than the [Group.Size].
+	Impossible
+
+	// Rejected means that the reservation wasn't made, as blocking it would
+	// exceed the max [Group.PendingCount] set via [Group.SetMaxPendingWeight].
+	Rejected
+
+	// NotAdmitted means that the reservation wasn't made, as it wasn't
+	// admitted by the gate set via [Group.SetAdmissionGate].
+	NotAdmitted
+)
+
+// Granted reports whether the reservation was made.
+func (o Outcome) Granted() bool {
+	return o == GrantedImmediately || o == GrantedAfterWait
+}
+
+// String returns the name of the [Outcome].
+func (o Outcome) String() string {
+	switch o {
+	case GrantedImmediately:
+		return "GrantedImmediately"
+	case GrantedAfterWait:
+		return "GrantedAfterWait"
+	case Aborted:
+		return "Aborted"
+	case Impossible:
+		return "Impossible"
+	case Rejected:
+		return "Rejected"
+	case NotAdmitted:
+		return "NotAdmitted"
+	default:
+		return "Outcome(" + strconv.Itoa(int(o)) + ")"
+	}
+}
+
+// ReserveNContext increments [Group.ActiveCount] by n, blocking if needed,
+// exactly like [Group.ReserveN] with ctx.Done() as the doneChan, but returns
+// the reason of the failure as an error, instead of a bool.
+//
+// It returns nil if the reservation was made, ctx.Err() if it was aborted
+// because ctx was done, [ErrMaxPendingWeight] if it was rejected by
+// [Group.SetMaxPendingWeight], and [ErrNotAdmitted] if it wasn't admitted by
+// [Group.SetAdmissionGate].
+//
+// Unlike [Group.ReserveN], it returns [ErrReserveTooLarge] right away if n is
+// greater than the [Group.Size], without waiting for ctx to be done.
+//
+// It panics if n is less than or equal to 0, or greater than [math.MaxInt32].
+func (g *Group) ReserveNContext(ctx context.Context, n int) error {
+	return g.reserveN(ctx.Done(), n, &reserveOpts{ctx: ctx}).err(ctx)
+}
+
+// ReserveContext increments [Group.ActiveCount] by 1, exactly like
+// [Group.ReserveNContext] with n of 1.
+func (g *Group) ReserveContext(ctx context.Context) error {
+	return g.ReserveNContext(ctx, 1)
+}
+
+// reserveCtx reserves n exactly like [Group.ReserveNContext], but if n is
+// greater than the [Group.Size], it waits for ctx to be done, exactly like
+// [Group.ReserveN] does, and returns ctx.Err().
+func (g *Group) reserveCtx(ctx context.Context, n int) error {
+	outcome := g.reserveN(ctx.Done(), n, &reserveOpts{ctx: ctx})
+	if outcome == Impossible {
+		if ctx.Done() != nil {
+			<-ctx.Done()
 		}
+		return ctx.Err()
+	}
+	return outcome.err(ctx)
+}
+
+// err returns the error of a reserve call that ended with the [Outcome],
+// with ctx.Done() as the doneChan, which is nil if it was granted.
+func (o Outcome) err(ctx context.Context) error {
+	switch o {
+	case GrantedImmediately, GrantedAfterWait:
+		return nil
+	case Impossible:
+		return ErrReserveTooLarge
+	case Rejected:
+		return ErrMaxPendingWeight
+	case NotAdmitted:
+		return ErrNotAdmitted
+	default:
+		return ctx.Err()
+	}
+}
+
+// ReserveNStatus increments [Group.ActiveCount] by n exactly like
+// [Group.ReserveN], but returns an [Outcome] describing how the call ended,
+// instead of a bool.
+//
+// Unlike [Group.ReserveN], it returns [Impossible] right away if n is greater
+// than the [Group.Size], without waiting for the provided doneChan.
+//
+// It panics if n is less than or equal to 0, or greater than [math.MaxInt32].
+func (g *Group) ReserveNStatus(doneChan <-chan struct{}, n int) Outcome {
+	return g.reserveN(doneChan, n, nil)
+}
+
+// GrantSource describes how a reserve call that blocked got its N, as
+// returned by [Group.ReserveNStatusEx].
+type GrantSource uint8
+
+const (
+	// SourceNone means that the call didn't block, or wasn't granted.
+	SourceNone GrantSource = iota
+
+	// SourceHandoff means that the call was woken up by a [Group.FreeN]
+	// call, or an aborted reserve call, and found the room it needs on
+	// its first check after that.
+	SourceHandoff
+
+	// SourceRecheck means that the call was woken up, but the room it
+	// needs was only found after re-checking the counters, because they
+	// were changed concurrently by other calls.
+	// Many of these grants suggest a lossy handoff under contention.
+	SourceRecheck
+)
+
+// String returns the name of the [GrantSource].
+func (s GrantSource) String() string {
+	switch s {
+	case SourceNone:
+		return "SourceNone"
+	case SourceHandoff:
+		return "SourceHandoff"
+	case SourceRecheck:
+		return "SourceRecheck"
+	default:
+		return "GrantSource(" + strconv.Itoa(int(s)) + ")"
+	}
+}
+
+// ReserveNStatusEx increments [Group.ActiveCount] by n exactly like
+// [Group.ReserveNStatus], and also returns a [GrantSource] describing how
+// the call got its n, if it was granted after blocking.
+// For any other [Outcome], the [GrantSource] is [SourceNone].
+//
+// It panics if n is less than or equal to 0, or greater than [math.MaxInt32].
+func (g *Group) ReserveNStatusEx(doneChan <-chan struct{}, n int) (Outcome, GrantSource) {
+	opts := &reserveOpts{}
+	outcome := g.reserveN(doneChan, n, opts)
+	if outcome != GrantedAfterWait {
+		return outcome, SourceNone
+	}
+
+	return outcome, opts.source
+}
+
+// reserveOpts holds the optional behaviors of a single reserve call, which
+// only apply once the call blocks.
+// a nil *reserveOpts means none of them is used.
+type reserveOpts struct {
+	// ungated skips the [Group.SetAdmissionGate] check, for the calls that
+	// can't report a rejection.
+	ungated bool
+
+	// stranded is set if the call was blocked, and [Group.Resize] shrank
+	// the size below its n, so it gave up.
+	stranded bool
+
+	// onPos is called with the estimated queue position of the call, each
+	// time it changes, while it's blocked.
+	onPos   func(pos int)
+	lastPos int
+
+	// timerChan aborts the call once it's receive-ready, in addition to
+	// the call's doneChan.
+	timerChan <-chan time.Time
+
+	// cancel aborts the call once it's set, in addition to the call's
+	// doneChan, and it's polled while the call is blocked.
+	cancel *atomic.Bool
+
+	// preempt makes the call receive from the preemptChan too, while it's
+	// blocked, so it's woken up before the other blocked calls.
+	preempt bool
+
+	// cond aborts the call if it returns false when the call is about
+	// to be granted after blocking.
+	cond func() bool
+
+	// source is set to how the call got its n, once it's granted
+	// after blocking.
+	source GrantSource
+
+	// available is set to the room in the active count, from the counter
+	// that removed the call's n from the pending count, once it's aborted
+	// after blocking.
+	available    int
+	availableSet bool
+
+	// saturated is set if the counter update that granted the call made
+	// the active count reach the size.
+	saturated bool
+
+	// ctx is the ctx of the ctx-based calls, whose pprof labels are kept
+	// while the call is blocked, if profiling is enabled.
+	ctx context.Context
+}
+
+func (o *reserveOpts) gated() bool {
+	return o == nil || !o.ungated
+}
+
+func (o *reserveOpts) timer() <-chan time.Time {
+	if o == nil {
+		return nil
+	}
+	return o.timerChan
+}
+
+func (o *reserveOpts) canceled() bool {
+	return o != nil && o.cancel != nil && o.cancel.Load()
+}
+
+func (o *reserveOpts) granting() bool {
+	return o == nil || o.cond == nil || o.cond()
+}
+
+func (o *reserveOpts) setAvailable(size uint32, counter uint64) {
+	if o == nil {
+		return
+	}
+	_, active := counterParts(counter)
+	o.available = max(int(size)-int(active), 0)
+	o.availableSet = true
+}
+
+func (o *reserveOpts) setSaturated(size uint32, newCounter uint64) {
+	if o == nil {
+		return
+	}
+	_, active := counterParts(newCounter)
+	o.saturated = int(active) == int(size)
+}
+
+func (o *reserveOpts) setSource(source GrantSource) {
+	if o == nil {
+		return
+	}
+	o.source = source
+}
+
+func (o *reserveOpts) reportPos(pending uint32, reserveN int) {
+	if o == nil || o.onPos == nil {
+		return
+	}
+
+	// the position is estimated as the N pending ahead of this call,
+	// which is all the pending N except this call's own.
+	pos := int(pending) - reserveN
+	if pos == o.lastPos {
+		return
+	}
+
+	o.lastPos = pos
+	o.onPos(pos)
+}
+
+func (g *Group) reserveN(doneChan <-chan struct{}, n int, opts *reserveOpts) Outcome {
+	outcome := g.reserveNLimited(doneChan, n, opts)
+
+	// the pacing is checked here, so it costs nothing unless it's enabled.
+	if outcome.Granted() && g.ext.Load().pacing() && !g.pace(doneChan, opts) {
+		// aborted while waiting for the min interval, so give
+		// the reserved n back.
+		g.FreeN(n)
+
+		return Aborted
+	}
+
+	return outcome
+}
+
+// reserveNLimited is reserveN without the pacing of [Group.SetMinInterval].
+func (g *Group) reserveNLimited(doneChan <-chan struct{}, n int, opts *reserveOpts) Outcome {
+	g.checkN(n, "reserve")
+
+	// return and don't update any counters if the provided doneChan
+	// is already closed.
+	if doneChan != nil {
+		select {
+		case <-doneChan:
+			return Aborted
+		default:
+		}
+	}
+	if timerChan := opts.timer(); timerChan != nil {
+		select {
+		case <-timerChan:
+			return Aborted
+		default:
+		}
+	}
+	if opts.canceled() {
+		return Aborted
+	}
+
+	// reject the call before touching the counter, if it's not admitted.
+	e := g.ext.Load()
+	if opts.gated() && !e.admitted() {
+		return NotAdmitted
+	}
+
+	// if the size is 0, then there's no limitation on the Reserve
+	// calls, and the call should succeed right away.
+	size := g.size.Load()
+	if size == 0 {
+		g.logOp(OpReserve, n, g.counter.Add(uint64(n)))
+
+		return GrantedImmediately
+	}
+
+	// if the size isn't enforced, then the call should succeed right away,
+	// but record if it would have blocked otherwise.
+	if !e.enforced() {
+		g.reserveDryRun(e, size, n)
+
+		return GrantedImmediately
+	}
+
+	// if the requested N is greater than the set size, then this
+	// Reserve call is destined to fail.
+	if n > int(size) {
+		return Impossible
+	}
+
+	// if the Reserve call can be made with the size limit, then
+	// the call should succeed right away.
+	// and if it can't be queued, then it should fail right away.
+	// only the calls that can be aborted can be rejected, as the others
+	// are committed to wait.
+	var maxPending int32
+	if e != nil && (doneChan != nil || opts.timer() != nil || (opts != nil && opts.cancel != nil)) {
+		maxPending = e.maxPendingWeight.Load()
+	}
+
+	reserved, queued := g.tryReserve(size, n, false, maxPending, opts)
+	if reserved {
+		if e != nil {
+			e.reserves.Add(1)
+		}
+		return GrantedImmediately
+	}
+	if !queued {
+		return Rejected
+	}
+
+	// the call is blocking anyway, so its opt-in state is reloaded, in case
+	// it was created since.
+	// only sample the time if slow reservations are logged.
+	var slow *slowReserve
+	var profiling bool
+	if e = g.ext.Load(); e != nil {
+		e.reserves.Add(1)
+		e.blocked.Add(1)
+		slow = e.slowReserve.Load()
+		profiling = e.profiling.Load()
+	}
+
+	// otherwise, block until matching FreeN calls are made.
+	var start time.Time
+	if slow != nil {
+		start = g.now()
+	}
+
+	// the blocked call records if it's stranded by a [Group.Resize].
+	if opts == nil {
+		opts = &reserveOpts{}
+	}
+
+	if profiling && opts.ctx != nil {
+		reserved = g.reserveNSlowProfiled(size, doneChan, n, opts)
+	} else {
+		reserved = g.reserveNSlow(size, doneChan, n, opts)
+	}
+
+	if !reserved {
+		if opts.stranded {
+			return Impossible
+		}
+		return Aborted
+	}
+
+	if slow != nil {
+		if waited := g.now().Sub(start); waited > slow.threshold {
+			slow.log(waited, n)
+		}
+	}
+	return GrantedAfterWait
+}
+
+// testHookBeforeGrant, if set, is called by a woken up reserve call right
+// before it updates the counter to take the room it found.
+var testHookBeforeGrant func()
+
+// blockChanLoadRetries is the number of times that reserveNSlow retries
+// loading a nil blockChan, while it's being set by a concurrent SetSize call.
+const blockChanLoadRetries = 100
+
+func (g *Group) reserveNSlow(size uint32, doneChan <-chan struct{}, reserveN int, opts *reserveOpts) (granted bool) {
+	// at this point, the blockChan shouldn't be nil.
+	// because we enter this method only if the size is not 0,
+	// and the blockChan will always be set if the size is not 0,
+	// unless the [Group.SetSize] method has been called concurrently
+	// with the [Group.ReserveN] method, and a context switch to
+	// this method happened before the [Group.SetSize] method has set
+	// the blockChan value, but right after setting the size value.
+	// in that case, the blockChan is about to be set, so retry loading
+	// it a few times before giving up.
+	blockChan := g.loadBlockChan()
+	for i := 0; blockChan == nil && i < blockChanLoadRetries; i++ {
+		runtime.Gosched()
+		blockChan = g.loadBlockChan()
+	}
+
+	if blockChan == nil {
+		g.panic("concurrent Reserve calls while initializing group")
+	}
+
+	// only the preemptive calls receive from the preemptChan, and the ext
+	// is always set for them, by [Group.ReservePreempt].
+	e := g.ext.Load()
+	var preemptChanVal chan struct{}
+	if opts != nil && opts.preempt {
+		preemptChanVal = e.preemptChan
+		e.blockedPreempters.Add(1)
+		defer e.blockedPreempters.Add(-1)
+	}
+
+	// log the blocked call, and how it ends, only if a logger is set.
+	if e != nil {
+		if l := e.logger.Load(); l != nil {
+			start := g.logBlocked(l, reserveN)
+			defer func() { g.logUnblocked(l, start, reserveN, granted, doneChan, opts) }()
+		}
+	}
+
+	if opts != nil {
+		pending, _ := counterParts(g.counter.Load())
+		opts.lastPos = -1
+		opts.reportPos(pending, reserveN)
+	}
+
+	// wait for a suitable freed tickets, or keep looping.
+	timerChan := opts.timer()
+
+	// poll the cancel flag, if any, as it can't be selected on.
+	var pollChan <-chan time.Time
+	if opts != nil && opts.cancel != nil {
+		ticker := time.NewTicker(flagPollInterval)
+		defer ticker.Stop()
+		pollChan = ticker.C
+	}
+
+	for {
+		select {
+		case <-blockChan:
+			// block for a FreeN call.
+			reloop, ok := g.reserveNSuccessWait(size, doneChan, reserveN, blockChan, opts)
+			if ok {
+				return true
+			}
+			if !reloop {
+				return false
+			}
+		case <-preemptChanVal:
+			// or for a FreeN call that prefers the preemptive calls.
+			reloop, ok := g.reserveNSuccessWait(size, doneChan, reserveN, blockChan, opts)
+			if ok {
+				return true
+			}
+			if !reloop {
+				return false
+			}
+		case <-doneChan:
+			// or abort on demand.
+			g.reserveNAbortWait(size, blockChan, reserveN, opts)
+			return false
+		case <-timerChan:
+			// or abort once the timer fires.
+			g.reserveNAbortWait(size, blockChan, reserveN, opts)
+			return false
+		case <-pollChan:
+			// or abort once the cancel flag is set.
+			if opts.canceled() {
+				g.reserveNAbortWait(size, blockChan, reserveN, opts)
+				return false
+			}
+		}
+	}
+}
+
+func (g *Group) reserveNSuccessWait(
+	size uint32,
+	doneChan <-chan struct{},
+	reserveN int,
+	blockChan chan struct{},
+	opts *reserveOpts,
+) (reloop, ok bool) {
+	// execute in a loop, because counterUpdate might lose the CAS.
+	source := SourceHandoff
+	for {
+		// the size might be changed by [Group.Resize] while blocked.
+		size = g.size.Load()
+
+		// if the size was shrunk below this call's n, then it can never
+		// succeed, so give up, and pass the wakeup on, as it can't use it.
+		if reserveN > int(size) {
+			g.reserveNAbortWait(size, blockChan, reserveN, opts)
+			opts.stranded = true
+			return false, false
+		}
+
+		counter := g.counter.Load()
+		pending, active := counterParts(counter)
+		diffN := int(size) - int(active) - reserveN
+
+		// if we got what we need, update the counter and return true.
+		if diffN >= 0 {
+			// only move forward if the doneChan wasn't closed,
+			// and the timer, if any, didn't fire.
+			select {
+			case <-doneChan:
+				g.reserveNAbortWait(size, blockChan, reserveN, opts)
+				return false, false
+			case <-opts.timer():
+				g.reserveNAbortWait(size, blockChan, reserveN, opts)
+				return false, false
+			default:
+			}
+
+			// and only if the caller still wants the room.
+			if opts.canceled() || !opts.granting() {
+				g.reserveNAbortWait(size, blockChan, reserveN, opts)
+				return false, false
+			}
+
+			if testHookBeforeGrant != nil {
+				testHookBeforeGrant()
+			}
+
+			newCounter, ok := g.counterUpdate(counter, -reserveN, reserveN)
+			if !ok {
+				// the counter got changed, re-loop and try again.
+				source = SourceRecheck
+				continue
+			}
+
+			opts.setSource(source)
+			opts.setSaturated(size, newCounter)
+			return false, true
+		}
+
+		// this call still needs more N to succeed...
+		// note: returning to wait below can't miss a wakeup, as this call's
+		// N is at most the size, so still needing more N means that the
+		// active count is at least 1, and its FreeN call is still due.
+		opts.reportPos(pending, reserveN)
+
+		// if this is the only blocked call, then return and wait for
+		// the next free call.
+		if int(pending) == reserveN {
+			return true, false
+		}
+
+		// if there are still potentially active calls, then return and wait
+		// for the next free call.
+		if int(pending)+int(active) > int(size) {
+			return true, false
+		}
+
+		// notify another blocked call to check its goal...
+		// note: if the code below didn't notify any reserve calls, it means
+		// it unblocked another call, which means the other call already
+		// updated the counter, so we need to re-loop and check again.
+		// otherwise, this call might be the last one, and we might end up
+		// waiting for a FreeN call that will never happen.
+
+		// only proceed if there are other pending calls.
+		// otherwise, yield before re-looping, as the counter can only change
+		// by other goroutines, which might not run until this one yields
+		// when there's a single P (GOMAXPROCS=1).
+		if int(pending)-reserveN <= 0 {
+			runtime.Gosched()
+			source = SourceRecheck
+			continue
+		}
+
+		// attempt to wake up a Reserve call, or unblock another that's trying the same.
+		select {
+		case blockChan <- struct{}{}:
+			// woke up 1 blocked Reserve call...
+			return true, false
+		case <-blockChan:
+			// unblock another call.
+			source = SourceRecheck
+			continue
+		}
+	}
+}
+
+func (g *Group) reserveNAbortWait(
+	size uint32,
+	blockChan chan struct{},
+	reserveN int,
+	opts *reserveOpts,
+) {
+	var counter uint64
+	for ok := false; !ok; {
+		counter = g.counter.Load()
+		counter, ok = g.counterUpdate(counter, -reserveN, 0)
+	}
+	opts.setAvailable(size, counter)
+
+	counter = g.notifyFree(blockChan)
+	g.notifyWait(counterParts(counter))
+}
+
+// AcquireUntil reserves n exactly like [Group.ReserveN], and if it succeeds,
+// it frees the reserved n once the provided releaseOn becomes receive-ready,
+// instead of requiring a [Group.FreeN] call.
+//
+// It returns false if the reservation was aborted via the provided doneChan,
+// in which case nothing is reserved and releaseOn is never watched.
+//
+// On success, a goroutine watches releaseOn and frees the reserved n exactly
+// once, then exits.
+// The reserved n must not be freed manually.
+//
+// It panics if n is less than or equal to 0, or if releaseOn is nil.
+//
+// Note: the releaseOn becomes receive-ready when it's closed or sent to.
+func (g *Group) AcquireUntil(doneChan <-chan struct{}, n int, releaseOn <-chan struct{}) bool {
+	if releaseOn == nil {
+		// a nil releaseOn would never free the reserved n.
+		g.panic("nil group release channel")
+	}
+
+	if !g.reserveNWait(doneChan, n) {
+		return false
+	}
+
+	go func() {
+		<-releaseOn
+		g.FreeN(n)
+	}()
+
+	return true
+}
+
+// Spawn reserves 1, blocking if needed, exactly like [Group.ReserveN], then
+// runs f in a new goroutine, and frees the reserved 1 once f returns.
+//
+// It combines the concurrency limit and the completion tracking of the
+// [Group] in a single call, as the spawned goroutines are limited by the
+// [Group.Size], and [Group.Wait] waits for them to complete.
+//
+// It returns ctx.Err() if the reservation was aborted because ctx was done,
+// or the error of the rejection if it was rejected, in which case f isn't run.
+func (g *Group) Spawn(ctx context.Context, f func()) error {
+	if err := g.reserveCtx(ctx, 1); err != nil {
+		return err
+	}
+
+	go func() {
+		defer g.Free()
+		f()
+	}()
+
+	return nil
+}
+
+// AcquireContext reserves n, blocking if needed, exactly like [Group.ReserveN]
+// with parent.Done() as the doneChan, and returns a context that's tied to
+// the ownership of the reserved n.
+//
+// The returned ctx is canceled when release is called, or when parent is
+// done, in which case the reserved n is freed as if release was called.
+// The release func is safe to call multiple times, and frees the reserved
+// n exactly once.
+//
+// It returns parent.Err() if the reservation was aborted because parent
+// was done, or the error of the rejection if it was rejected, in which case
+// ctx and release are nil.
+func (g *Group) AcquireContext(
+	parent context.Context,
+	n int,
+) (ctx context.Context, release func(), err error) {
+	if err := g.reserveCtx(parent, n); err != nil {
+		return nil, nil, err
+	}
+
+	free := sync.OnceFunc(func() { g.FreeN(n) })
+	ctx, cancel := context.WithCancel(parent)
+	stop := context.AfterFunc(parent, free)
+
+	release = func() {
+		stop()
+		cancel()
+		free()
+	}
+
+	return ctx, release, nil
+}
+
+// ReserveOrWait reserves n, blocking if needed, exactly like [Group.ReserveN]
+// with ctx.Done() as the doneChan, or waits until the [Group] reaches zero if
+// n can never be reserved, exactly like [Group.Wait].
+//
+// It returns true if n was reserved, or false with a nil error if the [Group]
+// reached zero instead, or false with ctx.Err() if ctx was done first, or
+// false with [ErrMaxPendingWeight] if it was rejected by
+// [Group.SetMaxPendingWeight], or false with [ErrNotAdmitted] if it wasn't
+// admitted by [Group.SetAdmissionGate].
+//
+// The [Group] can only reach zero instead if n is greater than the
+// [Group.Size], as the n of a blocked call is part of the [Group.PendingCount],
+// so the [Group] can't reach zero while it's blocked, and once there's room
+// for it, it's reserved.
+// So a reservation and a drain are never both possible for the same call.
+// If ctx is done at the same time the [Group] reaches zero, the drain wins.
+//
+// It panics if n is less than or equal to 0, or greater than [math.MaxInt32].
+func (g *Group) ReserveOrWait(ctx context.Context, n int) (reserved bool, err error) {
+	switch g.reserveN(ctx.Done(), n, &reserveOpts{ctx: ctx}) {
+	case GrantedImmediately, GrantedAfterWait:
+		return true, nil
+	case Impossible:
+		// n can never be reserved, so wait for the drain instead.
+	case Rejected:
+		return false, ErrMaxPendingWeight
+	case NotAdmitted:
+		return false, ErrNotAdmitted
+	default:
+		return false, ctx.Err()
+	}
+
+	waitChan := g.WaitChan()
+	select {
+	case <-waitChan:
+		return false, nil
+	case <-ctx.Done():
+		select {
+		case <-waitChan:
+			return false, nil
+		default:
+			return false, ctx.Err()
+		}
+	}
+}
+
+// ReserveNCtxInfo reserves n, blocking if needed, exactly like
+// [Group.ReserveN] with ctx.Done() as the doneChan, and on failure also
+// reports the room that was available, so the caller can retry right away
+// with a smaller n.
+//
+// If the call is aborted after blocking, the available room is read from
+// the same counter update that removed its n from the [Group.PendingCount].
+// Otherwise, it's read from the counter when the call fails.
+// The available room is the [Group.Size] minus the [Group.ActiveCount],
+// regardless of other blocked calls, and it's 0 on success.
+//
+// It returns ctx.Err() if ctx was done first, [ErrReserveTooLarge] if n is
+// greater than the [Group.Size], [ErrMaxPendingWeight] if it was
+// rejected by [Group.SetMaxPendingWeight], and [ErrNotAdmitted] if it wasn't
+// admitted by [Group.SetAdmissionGate].
+//
+// It panics if n is less than or equal to 0, or greater than [math.MaxInt32].
+func (g *Group) ReserveNCtxInfo(ctx context.Context, n int) (ok bool, available int, err error) {
+	opts := &reserveOpts{ctx: ctx}
+	outcome := g.reserveN(ctx.Done(), n, opts)
+	if outcome.Granted() {
+		return true, 0, nil
+	}
+
+	if !opts.availableSet {
+		opts.setAvailable(g.size.Load(), g.counter.Load())
+	}
+
+	switch outcome {
+	case Impossible:
+		err = ErrReserveTooLarge
+	case Rejected:
+		err = ErrMaxPendingWeight
+	case NotAdmitted:
+		err = ErrNotAdmitted
+	default:
+		err = ctx.Err()
+	}
+	return false, opts.available, err
+}
+
+// FreeOnDone frees n, exactly like [Group.FreeN], once ctx is done, via
+// [context.AfterFunc], which is meant for reservations that should be freed
+// on cancellation.
+//
+// The returned stop func is for the normal path: it cancels the free on ctx,
+// and frees n itself, unless ctx is already done and n was freed because of
+// it, in which case it does nothing.
+// So n is freed exactly once, either way, and the caller must not free it
+// on its own.
+// The stop func is safe to call multiple times.
+func (g *Group) FreeOnDone(ctx context.Context, n int) (stop func()) {
+	stopAfter := context.AfterFunc(ctx, func() { g.FreeN(n) })
+
+	return sync.OnceFunc(func() {
+		if stopAfter() {
+			// the free on ctx was canceled before it ran.
+			g.FreeN(n)
+		}
+	})
+}
+
+// Upgrade increments [Group.ActiveCount] by additional exactly like
+// [Group.ReserveN], for a caller that already holds currentHeld, so it
+// holds currentHeld+additional once it returns true.
+//
+// The currentHeld isn't freed while the call is blocked, so the caller keeps
+// its hold either way, and it must free currentHeld+additional on success,
+// or only currentHeld otherwise.
+//
+// It returns false right away if currentHeld+additional is greater than the
+// [Group.Size], without waiting for the provided doneChan, as the call would
+// otherwise wait for the caller's own currentHeld to be freed.
+//
+// It only avoids waiting on the caller's own hold, so two holders that
+// upgrade at the same time can deadlock, if neither additional fits until
+// the other holder frees its currentHeld.
+// Such upgrades should be aborted via the provided doneChan, or serialized.
+//
+// It panics if currentHeld is less than 0, or if additional is less than or
+// equal to 0, or if either is greater than [math.MaxInt32].
+func (g *Group) Upgrade(currentHeld, additional int, doneChan <-chan struct{}) bool {
+	g.checkN(additional, "reserve")
+
+	if currentHeld < 0 {
+		g.panic("invalid group held N value")
+	}
+
+	if currentHeld > maxN {
+		g.panic("group held N value out of range")
+	}
+
+	if g.exceedsSize(currentHeld + additional) {
+		return false
+	}
+
+	return g.reserveN(doneChan, additional, nil).Granted()
+}
+
+// TryReserveN tries to increment the [Group.ActiveCount] by n without
+// blocking and returns true if it was successful.
+// It returns false if the [Group.PendingCount] is not 0 or there's no
+// room in the [Group.ActiveCount] against the [Group.Size], or if it's not
+// admitted by the gate set via [Group.SetAdmissionGate].
+//
+// It always returns true if the [Group.Size] is 0, and it's admitted.
+//
+// It panics if n is less than or equal to 0, or greater than [math.MaxInt32],
+// or greater than a non-zero [Group.Size], exactly like [Group.ReserveN].
+func (g *Group) TryReserveN(n int) bool {
+	if !g.tryReserveN(n) {
+		return false
+	}
+
+	if g.ext.Load().pacing() && !g.tryPace() {
+		// the min interval hasn't elapsed yet, so give the reserved n back.
+		g.FreeN(n)
+
+		return false
+	}
+
+	return true
+}
+
+// tryReserveN is [Group.TryReserveN] without the pacing of
+// [Group.SetMinInterval].
+func (g *Group) tryReserveN(n int) bool {
+	g.checkN(n, "reserve")
+
+	// optimistic fast path for the common case of a group with room, no
+	// waiters, and no opt-in features in use: a single load and CAS, before
+	// any of the checks below, as there's nothing to gate, log or track.
+	// the active count can't carry into the pending count, as it stays
+	// within the size.
+	// on any failure, fall back to the full path.
+	e := g.ext.Load()
+	size := g.size.Load()
+	if e == nil && size != 0 {
+		counter := g.counter.Load()
+		if pending, active := counterParts(counter); pending == 0 && int(active)+n <= int(size) &&
+			g.counter.CompareAndSwap(counter, counter+uint64(n)) {
+			return true
+		}
+	}
+
+	if !e.admitted() {
+		return false
+	}
+
+	if size == 0 {
+		// no limitation on the Reserve calls, so the call is allowed.
+		g.logOp(OpReserve, n, g.counter.Add(uint64(n)))
+
+		return true
 	}
 
-	// if the size is 0, then there's no limitation on the Reserve
-	// calls, and the call should succeed right away.
-	size := g.size.Load()
-	if size == 0 {
-		g.counter.Add(uint64(n))
+	if !e.enforced() {
+		g.reserveDryRun(e, size, n)
 
 		return true
 	}
 
-	// if the requested N is greater than the set size, then this
-	// Reserve call is destined to fail, so wait for the done chan,
-	// if it's provided, and return failure.
 	if n > int(size) {
-		if doneChan != nil {
-			<-doneChan
+		if g.resized.Load() {
+			// the size isn't fixed, so it's not a programming error.
+			return false
 		}
-
-		return false
+		g.panic("reserve N exceeds group size")
 	}
 
-	// if the Reserve call can be made with the size limit, then
-	// the call should succeed right away.
-	if g.tryReserve(size, n, false) {
-		return true
+	reserved, _ := g.tryReserve(size, n, true, 0, nil)
+	return reserved
+}
+
+// TryReserveNOrShortfall tries to increment the [Group.ActiveCount] by n
+// without blocking, exactly like [Group.TryReserveN], and on failure also
+// reports how many resources it was short.
+//
+// The shortfall is n minus the resources that were available to this call,
+// computed from the same counter read that decided the failure.
+// If the [Group.PendingCount] is not 0, nothing is available to this call,
+// as the room belongs to the blocked calls, so the shortfall is n.
+// On success, the shortfall is 0.
+//
+// If there's room for n, but the min interval set via [Group.SetMinInterval]
+// hasn't elapsed yet, it fails with a shortfall of 0, as it's not short of
+// any resources, only early.
+//
+// It panics if n is less than or equal to 0, or greater than [math.MaxInt32].
+func (g *Group) TryReserveNOrShortfall(n int) (ok bool, shortfall int) {
+	ok, shortfall = g.tryReserveNOrShortfall(n)
+	if ok && g.ext.Load().pacing() && !g.tryPace() {
+		// the min interval hasn't elapsed yet, so give the reserved n back.
+		g.FreeN(n)
+
+		return false, 0
 	}
 
-	// otherwise, block until matching FreeN calls are made.
-	return g.reserveNSlow(size, doneChan, n)
+	return ok, shortfall
 }
 
-func (g *Group) reserveNSlow(size uint32, doneChan <-chan struct{}, reserveN int) bool {
-	// at this point, the blockChan shouldn't be nil.
-	// because we enter this method only if the size is not 0,
-	// and the blockChan will always be set if the size is not 0,
-	// unless the [Group.SetSize] method has been called concurrently
-	// with the [Group.ReserveN] method, and a context switch to
-	// this method happened before the [Group.SetSize] method has set
-	// the blockChan value, but right after setting the size value.
-	blockChan := g.blockChan.Load()
+// tryReserveNOrShortfall is [Group.TryReserveNOrShortfall] without the
+// pacing of [Group.SetMinInterval].
+func (g *Group) tryReserveNOrShortfall(n int) (ok bool, shortfall int) {
+	g.checkN(n, "reserve")
 
-	if blockChan == nil {
-		panic("sema.Group: concurrent Reserve calls while initializing group")
+	// nothing is available to a call that's not admitted.
+	e := g.ext.Load()
+	if !e.admitted() {
+		return false, n
 	}
 
-	blockChanVal := blockChan.(chan struct{})
+	size := g.size.Load()
+	if size == 0 {
+		// no limitation on the Reserve calls, so the call is allowed.
+		g.logOp(OpReserve, n, g.counter.Add(uint64(n)))
 
-	// wait for a suitable freed tickets, or keep looping.
-	for {
-		select {
-		case <-blockChanVal:
-			// block for a FreeN call.
-			reloop, ok := g.reserveNSuccessWait(size, doneChan, reserveN, blockChanVal)
-			if ok {
-				return true
-			}
-			if !reloop {
-				return false
-			}
-		case <-doneChan:
-			// or abort on demand.
-			g.reserveNAbortWait(blockChanVal, reserveN)
-			return false
-		}
+		return true, 0
+	}
+
+	if !e.enforced() {
+		g.reserveDryRun(e, size, n)
+
+		return true, 0
 	}
-}
 
-func (g *Group) reserveNSuccessWait(
-	size uint32,
-	doneChan <-chan struct{},
-	reserveN int,
-	blockChan chan struct{},
-) (reloop, ok bool) {
-	// execute in a loop, because counterUpdate might lose the CAS.
 	for {
 		counter := g.counter.Load()
 		pending, active := counterParts(counter)
-		diffN := int(size) - int(active) - reserveN
-
-		// if we got what we need, update the counter and return true.
-		if diffN >= 0 {
-			// only move forward if the doneChan wasn't closed.
-			select {
-			case <-doneChan:
-				g.reserveNAbortWait(blockChan, reserveN)
-				return false, false
-			default:
-			}
 
-			_, ok = g.counterUpdate(counter, -reserveN, reserveN)
-			if !ok {
-				// the counter got changed, re-loop and try again.
-				continue
-			}
-
-			return false, true
-		}
-
-		// this call still needs more N to succeed...
-		// if this is the only blocked call, then return and wait for
-		// the next free call.
-		if int(pending) == reserveN {
-			return true, false
-		}
-
-		// if there are still potentially active calls, then return and wait
-		// for the next free call.
-		if int(pending)+int(active) > int(size) {
-			return true, false
+		if pending != 0 {
+			return false, n
 		}
 
-		// notify another blocked call to check its goal...
-		// note: if the code below didn't notify any reserve calls, it means
-		// it unblocked another call, which means the other call already
-		// updated the counter, so we need to re-loop and check again.
-		// otherwise, this call might be the last one, and we might end up
-		// waiting for a FreeN call that will never happen.
-
-		// only proceed if there are other pending calls.
-		if int(pending)-reserveN <= 0 {
-			continue
+		available := max(int(size)-int(active), 0)
+		if n > available {
+			return false, n - available
 		}
 
-		// attempt to wake up a Reserve call, or unblock another that's trying the same.
-		select {
-		case blockChan <- struct{}{}:
-			// woke up 1 blocked Reserve call...
-			return true, false
-		case <-blockChan:
-			// unblock another call.
-			continue
+		if _, ok := g.counterUpdate(counter, 0, n); ok {
+			return true, 0
 		}
 	}
 }
 
-func (g *Group) reserveNAbortWait(blockChan chan struct{}, reserveN int) {
-	for ok := false; !ok; {
-		counter := g.counter.Load()
-		counter, ok = g.counterUpdate(counter, -reserveN, 0)
-	}
-
-	counter := g.notifyFree(blockChan)
-	g.notifyWait(counterParts(counter))
-}
-
-// TryReserveN tries to increment the [Group.ActiveCount] by n without
-// blocking and returns true if it was successful.
-// It returns false if the [Group.PendingCount] is not 0 or there's no
-// room in the [Group.ActiveCount] against the [Group.Size].
+// ReserveNNoQueue tries to increment the [Group.ActiveCount] by n without
+// blocking, exactly like [Group.TryReserveN], but returns false right away
+// if the provided doneChan is already receive-ready.
 //
-// It always returns true if the [Group.Size] is 0.
+// It never increments the [Group.PendingCount] and never blocks, which suits
+// load-shedding callers that don't want to queue, but still want to honor
+// an already canceled context.
 //
-// It panics if n is less than or equal to 0.
-func (g *Group) TryReserveN(n int) bool {
-	if n <= 0 {
-		// n can't be 0 or negative, as 0 isn't a valid resource,
-		// and for negative values, [Group.FreeN] should be used.
-		panic("sema.Group: invalid group reserve N value")
-	}
-
-	size := g.size.Load()
-	if size == 0 {
-		// no limitation on the Reserve calls, so the call is allowed.
-		g.counter.Add(uint64(n))
+// It panics if n is less than or equal to 0, or greater than [math.MaxInt32],
+// or greater than a non-zero [Group.Size], exactly like [Group.TryReserveN].
+func (g *Group) ReserveNNoQueue(doneChan <-chan struct{}, n int) bool {
+	g.checkN(n, "reserve")
 
-		return true
-	}
-
-	if n > int(size) {
-		return false
+	// return and don't update any counters if the provided doneChan
+	// is already closed.
+	if doneChan != nil {
+		select {
+		case <-doneChan:
+			return false
+		default:
+		}
 	}
 
-	return g.tryReserve(size, n, true)
+	return g.TryReserveN(n)
 }
 
-func (g *Group) tryReserve(size uint32, reserveN int, tryCall bool) bool {
+func (g *Group) tryReserve(
+	size uint32,
+	reserveN int,
+	tryCall bool,
+	maxPending int32,
+	opts *reserveOpts,
+) (reserved, queued bool) {
 	for {
 		counter := g.counter.Load()
 		pending, active := counterParts(counter)
 
 		// if the group has room and doesn't have any waiters
 		if pending == 0 && int(active)+reserveN <= int(size) {
-			_, ok := g.counterUpdate(counter, 0, reserveN)
+			newCounter, ok := g.counterUpdate(counter, 0, reserveN)
 			if ok {
-				return true
+				opts.setSaturated(size, newCounter)
+				return true, false
 			}
 			continue
 		} else if !tryCall {
+			// reject the call if blocking it would exceed the max pending.
+			if maxPending > 0 && int(pending)+reserveN > int(maxPending) {
+				return false, false
+			}
+
 			_, ok := g.counterUpdate(counter, reserveN, 0)
 			if ok {
-				return false
+				return false, true
 			}
 			continue
 		} else {
-			return false
+			return false, false
 		}
 	}
 }
@@ -429,6 +1993,16 @@ func (g *Group) tryReserve(size uint32, reserveN int, tryCall bool) bool {
 //
 // If the [Group.ActiveCount] goes below zero by this call, it panics.
 func (g *Group) Free() {
+	// fast path for a size of 1: reserved with no blocked calls.
+	if g.single.Load() && g.counter.CompareAndSwap(1, 0) {
+		if e := g.ext.Load(); e != nil {
+			g.logOp(OpFree, 1, 0)
+			e.freesWithNoWaiter.Add(1)
+		}
+		g.notifyWait(0, 0)
+		return
+	}
+
 	g.FreeN(1)
 }
 
@@ -440,38 +2014,173 @@ func (g *Group) Free() {
 // calls blocked on [Group.Wait], and closes the [Group.WaitChan].
 //
 // If the [Group.ActiveCount] goes below zero by this call, it panics.
+// For a [Group] with a non-zero size, it panics before updating anything,
+// so the blocked calls aren't woken up by the invalid call.
 //
-// It panics if n is less than or equal to 0.
+// It panics if n is less than or equal to 0, or greater than [math.MaxInt32].
 func (g *Group) FreeN(n int) {
-	if n <= 0 {
-		// n can't be 0 or negative, as 0 isn't a valid resource,
-		// and for negative values, [Group.FreeN] should be used.
-		panic("sema.Group: invalid group free N value")
-	}
+	g.checkN(n, "free")
 
 	// check if we should wake up any blocked [Group.ReserveN] calls.
 	// note: if the blockChan is nil, then pending must be 0, as
 	// pending is used to track blocked calls, and blockChan is
 	// only set when the Group can have blocked calls (size != 0).
-	blockChan := g.blockChan.Load()
+	blockChan := g.loadBlockChan()
 
 	// update the counter, and read its values.
+	// waitActive is the active count that the Wait calls are checked against.
 	var pending uint32
-	var active int32
+	var active, waitActive int32
 	if blockChan == nil {
 		counter := g.counter.Add(uint64(-n))
+		g.logOp(OpFree, n, counter)
 		active = int32(counter)
+		waitActive = active
 	} else {
 		for ok := false; !ok; {
 			counter := g.counter.Load()
+
+			// handle any misuse before updating the counter, as the freed
+			// room would otherwise be handed off to the blocked calls,
+			// which would then be granted room that was never reserved.
+			if _, active = counterParts(counter); int(active) < n {
+				g.logOverFree(n, active)
+				g.panic("negative group counter")
+			}
+
 			counter, ok = g.counterUpdate(counter, 0, -n)
-			_, active = counterParts(counter)
+			pending, active = counterParts(counter)
 		}
+		waitActive = active
 
 		// notify any blocked ReserveN calls of the counter update,
 		// and make sure the counter values are updated.
-		counter := g.notifyFree(blockChan.(chan struct{}))
-		pending, _ = counterParts(counter)
+		// note: if there were no blocked calls when the counter was updated,
+		// then there's no one to notify, as any later ReserveN call sees
+		// the freed room, so it doesn't block for it.
+		// note: the woken up calls might be granted by then, so both values
+		// are reloaded, as the active count from the update above would
+		// miss their n, and wake up the Wait calls too early.
+		if pending == 0 {
+			if e := g.ext.Load(); e != nil {
+				e.freesWithNoWaiter.Add(1)
+			}
+		} else {
+			counter := g.notifyFree(blockChan)
+			pending, waitActive = counterParts(counter)
+		}
+	}
+
+	// attempt to wake up any blocked [Group.Wait] calls.
+	g.notifyWait(pending, waitActive)
+
+	// handle any misuse, assuming valid usage so far.
+	if active < 0 {
+		g.logOverFree(n, active+int32(n))
+		g.panic("negative group counter")
+	}
+}
+
+// Downgrade frees release of the currentHeld held by the caller, exactly
+// like [Group.FreeN], and returns the remaining held count, which is
+// currentHeld-release.
+//
+// The freed room wakes up the blocked calls it fits, exactly like
+// [Group.FreeN] does.
+//
+// It panics if release is less than or equal to 0, or greater than
+// currentHeld, or greater than [math.MaxInt32].
+func (g *Group) Downgrade(currentHeld, release int) int {
+	if release > currentHeld {
+		// can't release more than what's held.
+		g.panic("group release N value exceeds held N")
+	}
+
+	g.FreeN(release)
+	return currentHeld - release
+}
+
+// TryAdmit tries to increment the [Group.ActiveCount] by n without blocking
+// and returns true if it was successful.
+// Unlike [Group.TryReserveN], it ignores the [Group.PendingCount], and only
+// checks for room in the [Group.ActiveCount] against the [Group.Size].
+//
+// Together with [Group.ReleaseSlots], it lets callers that manage their own
+// admission queue use the [Group] as a pure counter, bypassing its built-in
+// wait machinery.
+// Mixing them with [Group.Reserve] and [Group.ReserveN] calls on the same
+// [Group] is unfair to the blocked calls, as TryAdmit can take the room they
+// are waiting for, and [Group.ReleaseSlots] doesn't wake them up.
+// So a [Group] should use either these methods or the reserve methods,
+// but not both.
+//
+// It always returns true if the [Group.Size] is 0.
+//
+// It panics if n is less than or equal to 0, or greater than [math.MaxInt32].
+func (g *Group) TryAdmit(n int) bool {
+	g.checkN(n, "reserve")
+
+	size := g.size.Load()
+	if size == 0 {
+		// no limitation on the admission, so the call is allowed.
+		g.logOp(OpReserve, n, g.counter.Add(uint64(n)))
+
+		return true
+	}
+
+	if n > int(size) {
+		return false
+	}
+
+	for {
+		counter := g.counter.Load()
+		_, active := counterParts(counter)
+
+		if int(active)+n > int(size) {
+			return false
+		}
+
+		if _, ok := g.counterUpdate(counter, 0, n); ok {
+			return true
+		}
+	}
+}
+
+// ReleaseSlots decrements the [Group.ActiveCount] by n, like [Group.FreeN],
+// but without attempting to wake up any blocked [Group.Reserve] or
+// [Group.ReserveN] calls.
+// It's the counterpart of [Group.TryAdmit], and the same notes apply.
+//
+// If the [Group.ActiveCount] reaches zero by this call, and there are no
+// pending calls, it will wake up any calls blocked on [Group.Wait], and
+// closes the [Group.WaitChan].
+//
+// If the [Group.ActiveCount] goes below zero by this call, it panics.
+//
+// It panics if n is less than or equal to 0, or greater than [math.MaxInt32].
+func (g *Group) ReleaseSlots(n int) {
+	g.checkN(n, "free")
+
+	var pending uint32
+	var active int32
+	if g.blockChan.Load() == nil {
+		counter := g.counter.Add(uint64(-n))
+		g.logOp(OpFree, n, counter)
+		active = int32(counter)
+	} else {
+		for ok := false; !ok; {
+			counter := g.counter.Load()
+
+			// handle any misuse before updating the counter, exactly like
+			// [Group.FreeN], so an invalid call doesn't update anything.
+			if _, active = counterParts(counter); int(active) < n {
+				g.logOverFree(n, active)
+				g.panic("negative group counter")
+			}
+
+			counter, ok = g.counterUpdate(counter, 0, -n)
+			pending, active = counterParts(counter)
+		}
 	}
 
 	// attempt to wake up any blocked [Group.Wait] calls.
@@ -479,20 +2188,36 @@ func (g *Group) FreeN(n int) {
 
 	// handle any misuse, assuming valid usage so far.
 	if active < 0 {
-		panic("sema.Group: negative group counter")
+		g.logOverFree(n, active+int32(n))
+		g.panic("negative group counter")
 	}
 }
 
 func (g *Group) notifyFree(blockChan chan struct{}) (counter uint64) {
 	counter = g.counter.Load()
 	pending, _ := counterParts(counter)
+	e := g.ext.Load()
 
 	// this will avoid Free missing an opportunity to wake up a Reserve.
 	for int(pending) > 0 {
+		// prefer waking up a blocked preemptive call, if there's any.
+		if e != nil && e.preempters.Load() > 0 {
+			select {
+			case e.preemptChan <- struct{}{}:
+				e.freesThatWokeWaiter.Add(1)
+				counter = g.counter.Load()
+				return counter
+			default:
+			}
+		}
+
 		// attempt to wakeup a Reserve call, or update pending until it's 0.
 		select {
 		case blockChan <- struct{}{}:
 			// woke up 1 blocked Reserve call...
+			if e != nil {
+				e.freesThatWokeWaiter.Add(1)
+			}
 			counter = g.counter.Load()
 			return counter
 		default:
@@ -504,10 +2229,20 @@ func (g *Group) notifyFree(blockChan chan struct{}) (counter uint64) {
 		}
 	}
 
+	if e != nil {
+		e.freesWithNoWaiter.Add(1)
+	}
 	return counter
 }
 
 func (g *Group) notifyWait(pending uint32, active int32) {
+	notifyWait(&g.waitChan, pending, active)
+}
+
+// notifyWait closes the chan stored in waitChan, as returned by
+// initWaitChan, once the counter it's for reaches zero.
+// It's shared by the [Group] and the [Mutex].
+func notifyWait(waitChan *atomic.Value, pending uint32, active int32) {
 	// if there are still blocked calls, as this means we still don't need
 	// to wake up any [Group.Wait] calls.
 	// return if there are still active calls, as this means we still don't
@@ -516,31 +2251,84 @@ func (g *Group) notifyWait(pending uint32, active int32) {
 		return
 	}
 
-	// waitChan will be nil only if no Wait calls have been made.
-	waitChan := g.waitChan.Load()
-	if waitChan == nil || waitChan == nilChan {
+	// waitChanVal will be nil only if no Wait calls have been made.
+	waitChanVal := waitChan.Load()
+	if waitChanVal == nil || waitChanVal == nilChan {
 		return
 	}
 
-	if !g.waitChan.CompareAndSwap(waitChan, nilChan) {
+	if !waitChan.CompareAndSwap(waitChanVal, nilChan) {
 		// if it didn't succeed, return, as it means that this value is
 		// an waitChan value, and a newer once has been set, after the old
 		// one got already closed.
 		return
 	}
 
-	close(waitChan.(chan struct{}))
+	close(waitChanVal.(chan struct{}))
 }
 
 // Wait blocks until the [Group] reaches zero.
 // It waits only for [Group.Reserve] and [Group.ReserveN] calls that are
 // either made before this function is called, or made while the [Group] is non-zero.
 // A zero [Group] means both [Group.ActiveCount] and [Group.PendingCount] are zero.
+//
+// Like the Add and Wait calls of a [sync.WaitGroup], a reserve call made on
+// a zero [Group] must happen before the Wait call to be waited for.
 func (g *Group) Wait() {
+	g.waiters.Add(1)
+	defer g.waiters.Add(-1)
+
 	waitChan := g.initWaitChan()
 	<-waitChan
 }
 
+// WaiterCount returns the number of goroutines that are currently blocked
+// in [Group.Wait] or [Group.WaitWithProgress] calls.
+// The goroutines that wait on the [Group.WaitChan] aren't counted.
+//
+// It's meant for diagnosing many goroutines waiting on the same [Group].
+func (g *Group) WaiterCount() int {
+	return int(g.waiters.Load())
+}
+
+// waitProgressInterval is the interval between the onStuck calls made by
+// [Group.WaitWithProgress].
+const waitProgressInterval = time.Second
+
+// WaitWithProgress blocks exactly like [Group.Wait], but calls the provided
+// onStuck func with the [Group.ActiveCount] and [Group.PendingCount] every
+// second while it's blocked, and returns true once the [Group] reaches zero.
+//
+// If onStuck returns true, the wait is aborted, and it returns false.
+// This gives a hook to diagnose, or to break out of, a wait that never ends,
+// like when a [Group] is used as a counter and a [Group.Free] call is missed.
+//
+// It panics if onStuck is nil.
+func (g *Group) WaitWithProgress(onStuck func(active, pending int) bool) bool {
+	if onStuck == nil {
+		g.panic("nil group wait onStuck func")
+	}
+
+	g.waiters.Add(1)
+	defer g.waiters.Add(-1)
+
+	waitChan := g.initWaitChan()
+	for {
+		timerChan, stop := g.newTimer(waitProgressInterval)
+		select {
+		case <-waitChan:
+			stop()
+			return true
+		case <-timerChan:
+		}
+
+		pending, active := counterParts(g.counter.Load())
+		if onStuck(int(active), int(pending)) {
+			return false
+		}
+	}
+}
+
 // WaitChan returns a channel that will be closed once the [Group] reaches zero.
 // It waits only for [Group.Reserve] and [Group.ReserveN] calls that are
 // either made before this function is called, or made while the [Group] is non-zero.
@@ -549,7 +2337,31 @@ func (g *Group) WaitChan() <-chan struct{} {
 	return g.initWaitChan()
 }
 
+// OnBatchComplete registers f to be called once, when the [Group] next
+// reaches zero, exactly like a [Group.Wait] call that's made now, and
+// then f is unregistered.
+//
+// If the [Group] is already zero, f is called immediately, in the calling
+// goroutine, before OnBatchComplete returns.
+// Otherwise, f is called in a new goroutine once the [Group] reaches zero,
+// even if new reserve calls are made after that.
+func (g *Group) OnBatchComplete(f func()) {
+	waitChan := g.initWaitChan()
+	if waitChan == closedChan {
+		f()
+		return
+	}
+
+	go func() {
+		<-waitChan
+		f()
+	}()
+}
+
 var closedChan = make(chan struct{})
+
+// nilChan is stored in waitChan once it's closed, until the next Wait call.
+// it's a nil chan, so it's distinct from any valid waitChan value.
 var nilChan chan struct{}
 
 func init() {
@@ -557,29 +2369,36 @@ func init() {
 }
 
 func (g *Group) initWaitChan() chan struct{} {
-	waitChan := g.waitChan.Load()
+	return initWaitChan(&g.waitChan, &g.counter)
+}
 
-	pending, active := counterParts(g.counter.Load())
+// initWaitChan returns the chan stored in waitChan, which is closed once
+// the provided counter reaches zero, creating it if needed.
+// It's shared by the [Group] and the [Mutex].
+func initWaitChan(waitChan *atomic.Value, counter *atomic.Uint64) chan struct{} {
+	waitChanVal := waitChan.Load()
+
+	pending, active := counterParts(counter.Load())
 	if pending <= 0 && active <= 0 {
 		return closedChan
 	}
 
-	if waitChan != nil && waitChan != nilChan {
-		return waitChan.(chan struct{})
+	if waitChanVal != nil && waitChanVal != nilChan {
+		return waitChanVal.(chan struct{})
 	}
 
-	return g.initWaitChanSlow(waitChan)
+	return initWaitChanSlow(waitChan, counter, waitChanVal)
 }
 
-func (g *Group) initWaitChanSlow(waitChan any) chan struct{} {
-	// we entered this method with waitChan either nil or nilChan,
+func initWaitChanSlow(waitChan *atomic.Value, counter *atomic.Uint64, waitChanVal any) chan struct{} {
+	// we entered this method with waitChanVal either nil or nilChan,
 	// and it can only go to nilChan or a valid chan value.
 	newWaitChan := make(chan struct{})
 
-	if g.waitChan.CompareAndSwap(waitChan, newWaitChan) {
+	if waitChan.CompareAndSwap(waitChanVal, newWaitChan) {
 		// we need to be sure that the swapped waitChan will be closed by
 		// a Free call, which happens only if the counter is still not 0.
-		pending, active := counterParts(g.counter.Load())
+		pending, active := counterParts(counter.Load())
 		if pending <= 0 && active <= 0 {
 			return closedChan
 		}
@@ -600,10 +2419,16 @@ func (g *Group) initWaitChanSlow(waitChan any) chan struct{} {
 	// be executed before their respective Wait calls.
 	// note: comparing only against nilChan, because it can't be nil
 	// again at this point.
-	loadedWaitChan := g.waitChan.Load()
+	loadedWaitChan := waitChan.Load()
 	if loadedWaitChan == nilChan {
 		return closedChan
 	}
 
 	return loadedWaitChan.(chan struct{})
 }
+
+// loadBlockChan returns the blockChan, which is nil until it's set.
+func (g *Group) loadBlockChan() chan struct{} {
+	blockChan, _ := g.blockChan.Load().(chan struct{})
+	return blockChan
+}
@@ -10,6 +10,7 @@
 package sema_test
 
 import (
+	"sync"
 	"sync/atomic"
 	"testing"
 
@@ -103,3 +104,103 @@ func TestSemaGroupAlign(t *testing.T) {
 	}(&x)
 	x.sg.Wait()
 }
+
+// the same tests on the [sema.WaitGrouper] adapter, on both an unbounded and
+// a size-limited [sema.Group], with the [sync.WaitGroup] as a reference.
+
+var _ sema.WaitGrouper = (*sync.WaitGroup)(nil)
+
+func testWaitGrouper(t *testing.T, wg1 sema.WaitGrouper, wg2 sema.WaitGrouper) {
+	n := 16
+	wg1.Add(n)
+	wg2.Add(n)
+	exited := make(chan bool, n)
+	for i := 0; i != n; i++ {
+		go func() {
+			wg1.Done()
+			wg2.Wait()
+			exited <- true
+		}()
+	}
+	wg1.Wait()
+	for i := 0; i != n; i++ {
+		select {
+		case <-exited:
+			t.Fatal("sema.WaitGrouper: released group too soon")
+		default:
+		}
+		wg2.Done()
+	}
+	for i := 0; i != n; i++ {
+		<-exited // Will block if barrier fails to unlock someone.
+	}
+}
+
+func TestSemaGroupAsWaitGroup(t *testing.T) {
+	for _, size := range []int{0, 16} {
+		wg1 := sema.NewGroup(size).AsWaitGroup()
+		wg2 := sema.NewGroup(size).AsWaitGroup()
+
+		// Run the same test a few times to ensure barrier is in a proper state.
+		for i := 0; i != 8; i++ {
+			testWaitGrouper(t, wg1, wg2)
+		}
+	}
+}
+
+func TestSemaGroupAsWaitGroupMisuse(t *testing.T) {
+	for _, size := range []int{0, 2} {
+		func() {
+			defer func() {
+				err := recover()
+				if err != "sema.Group: negative group counter" {
+					t.Fatalf("Unexpected panic: %#v", err)
+				}
+			}()
+			wg := sema.NewGroup(size).AsWaitGroup()
+			wg.Add(1)
+			wg.Done()
+			wg.Done()
+			t.Fatal("Should panic")
+		}()
+	}
+}
+
+func TestSemaGroupAsWaitGroupUngated(t *testing.T) {
+	sg := sema.NewGroup(2)
+	sg.SetAdmissionGate(func() bool { return false })
+
+	// the gate doesn't refuse Add, so the matching Done doesn't panic.
+	wg := sg.AsWaitGroup()
+	wg.Add(1)
+	if active := sg.ActiveCount(); active != 1 {
+		t.Fatalf("Group active count should be 1, got %d", active)
+	}
+	wg.Done()
+	wg.Wait()
+}
+
+func TestSemaGroupAsWaitGroupRace(t *testing.T) {
+	// Run this test for about 1ms.
+	for i := 0; i < 1000; i++ {
+		wg := sema.NewGroup(2).AsWaitGroup()
+		n := new(int32)
+		// spawn goroutine 1
+		wg.Add(1)
+		go func() {
+			atomic.AddInt32(n, 1)
+			wg.Done()
+		}()
+		// spawn goroutine 2
+		wg.Add(1)
+		go func() {
+			atomic.AddInt32(n, 1)
+			wg.Add(-1)
+		}()
+		// Wait for goroutine 1 and 2
+		wg.Wait()
+		if atomic.LoadInt32(n) != 2 {
+			t.Fatalf("Spurious wakeup from Wait @ i = %d", i)
+		}
+	}
+}
@@ -2,7 +2,12 @@ package sema_test
 
 import (
 	"context"
+	"errors"
+	"math"
+	"runtime"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -140,6 +145,10 @@ func helperTestGroupRaceWait(t *testing.T) {
 
 	// wait for all ongoing sg.Wait calls, without pre-initiating the WaitChan.
 	wg.Wait()
+
+	if waiters := sg.WaiterCount(); waiters != 0 {
+		t.Errorf("Group waiter count should be 0, got %d", waiters)
+	}
 }
 
 // merely returning from it without any panics is a success.
@@ -324,9 +333,11 @@ func TestGroupCounters(t *testing.T) {
 			if active := sg.ActiveCount(); active != 1 {
 				t.Errorf("Group active count should be 1, got %d", active)
 			}
-			// at least n,
-			if pending := sg.PendingCount(); pending < n || pending > 2*n {
-				t.Errorf("Group pending count should be %d, got %d", n, pending)
+			// two ReserveN(doneChan, n) calls are made below, and either
+			// one or both of them can be blocked at this point, so pending
+			// is exactly the N of the blocked calls, which is n or 2*n.
+			if pending := sg.PendingCount(); pending != n && pending != 2*n {
+				t.Errorf("Group pending count should be %d or %d, got %d", n, 2*n, pending)
 			}
 
 			close(doneChan)
@@ -404,3 +415,2060 @@ func TestGroupCounters(t *testing.T) {
 		}
 	})
 }
+
+func TestGroupAcquireUntil(t *testing.T) {
+	t.Parallel()
+
+	t.Run("the reservation is freed once releaseOn is closed", func(t *testing.T) {
+		sg := sema.NewGroup(2)
+
+		releaseOn := make(chan struct{})
+		if !sg.AcquireUntil(nil, 2, releaseOn) {
+			t.Fatalf("AcquireUntil should succeed")
+		}
+		if active := sg.ActiveCount(); active != 2 {
+			t.Errorf("Group active count should be 2, got %d", active)
+		}
+
+		close(releaseOn)
+		sg.Wait()
+
+		if active := sg.ActiveCount(); active != 0 {
+			t.Errorf("Group active count should be 0, got %d", active)
+		}
+	})
+
+	t.Run("the reservation fails cleanly if aborted", func(t *testing.T) {
+		sg := sema.NewGroup(1)
+		sg.Reserve()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		releaseOn := make(chan struct{})
+		close(releaseOn)
+		if sg.AcquireUntil(ctx.Done(), 1, releaseOn) {
+			t.Fatalf("AcquireUntil should fail")
+		}
+
+		// give a wrongly started watcher a chance to free.
+		time.Sleep(10 * time.Millisecond)
+
+		if active := sg.ActiveCount(); active != 1 {
+			t.Errorf("Group active count should be 1, got %d", active)
+		}
+		if pending := sg.PendingCount(); pending != 0 {
+			t.Errorf("Group pending count should be 0, got %d", pending)
+		}
+		sg.Free()
+	})
+}
+
+func TestGroupTryAdmit(t *testing.T) {
+	t.Parallel()
+
+	sg := sema.NewGroup(2)
+	sg.Reserve()
+
+	// make a ReserveN call block, so the pending count isn't 0.
+	reserved := make(chan struct{})
+	go func() {
+		sg.ReserveN(nil, 2)
+		close(reserved)
+	}()
+	for sg.PendingCount() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// TryReserveN fails because of the pending call, but TryAdmit doesn't.
+	if sg.TryReserveN(1) {
+		t.Fatalf("TryReserveN should fail while there are pending calls")
+	}
+	if !sg.TryAdmit(1) {
+		t.Fatalf("TryAdmit should succeed while there's room")
+	}
+	if sg.TryAdmit(1) {
+		t.Fatalf("TryAdmit should fail while there's no room")
+	}
+	if pending := sg.PendingCount(); pending != 2 {
+		t.Errorf("Group pending count should be 2, got %d", pending)
+	}
+
+	// ReleaseSlots doesn't wake up the blocked call.
+	sg.ReleaseSlots(2)
+	select {
+	case <-reserved:
+		t.Fatalf("ReleaseSlots shouldn't wake up blocked calls")
+	case <-time.After(10 * time.Millisecond):
+	}
+	if active := sg.ActiveCount(); active != 0 {
+		t.Errorf("Group active count should be 0, got %d", active)
+	}
+
+	// a later free wakes it up.
+	sg.TryAdmit(1)
+	sg.Free()
+	<-reserved
+	sg.FreeN(2)
+	sg.Wait()
+}
+
+func TestGroupNamedMisuse(t *testing.T) {
+	defer func() {
+		err := recover()
+		if err != `sema.Group "downloads": negative group counter` {
+			t.Fatalf("Unexpected panic: %#v", err)
+		}
+	}()
+	sg := sema.NewNamedGroup("downloads", 0)
+	sg.Reserve()
+	sg.Free()
+	sg.Free()
+	t.Fatal("Should panic")
+}
+
+// merely returning from it without any Reserve panics is a success.
+func TestGroupSetSizeRace(t *testing.T) {
+	t.Parallel()
+
+	for range 1000 {
+		sg := &sema.Group{}
+
+		wg := sync.WaitGroup{}
+		wg.Add(3)
+
+		go func() {
+			defer wg.Done()
+			// SetSize is allowed to panic when racing with Reserve calls.
+			defer func() { recover() }()
+			sg.SetSize(1)
+		}()
+
+		for range 2 {
+			go func() {
+				defer wg.Done()
+
+				// a Free call that races with SetSize might not wake up
+				// this call, so don't wait for it forever.
+				ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+				defer cancel()
+
+				if sg.ReserveN(ctx.Done(), 1) {
+					sg.Free()
+				}
+			}()
+		}
+
+		wg.Wait()
+	}
+}
+
+func TestGroupSpawn(t *testing.T) {
+	t.Parallel()
+
+	t.Run("the spawned tasks are bounded and waited for", func(t *testing.T) {
+		size := 4
+		tasks := 32
+		sg := sema.NewGroup(size)
+
+		var mu sync.Mutex
+		running, maxRunning, completed := 0, 0, 0
+		for range tasks {
+			err := sg.Spawn(context.Background(), func() {
+				mu.Lock()
+				running++
+				maxRunning = max(maxRunning, running)
+				mu.Unlock()
+
+				time.Sleep(time.Millisecond)
+
+				mu.Lock()
+				running--
+				completed++
+				mu.Unlock()
+			})
+			if err != nil {
+				t.Fatalf("Spawn should succeed, got %v", err)
+			}
+		}
+
+		sg.Wait()
+
+		mu.Lock()
+		defer mu.Unlock()
+		if maxRunning > size {
+			t.Errorf("running tasks should be at most %d, got %d", size, maxRunning)
+		}
+		if completed != tasks {
+			t.Errorf("completed tasks should be %d, got %d", tasks, completed)
+		}
+	})
+
+	t.Run("the ctx error is returned if aborted", func(t *testing.T) {
+		sg := sema.NewGroup(1)
+		sg.Reserve()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		if err := sg.Spawn(ctx, func() { t.Errorf("f shouldn't run") }); err != context.Canceled {
+			t.Errorf("Spawn should fail with %v, got %v", context.Canceled, err)
+		}
+		sg.Free()
+	})
+}
+
+func TestGroupReserveNStatus(t *testing.T) {
+	t.Parallel()
+
+	sg := sema.NewGroup(2)
+
+	if got := sg.ReserveNStatus(nil, 1); got != sema.GrantedImmediately {
+		t.Errorf("ReserveNStatus should return %v, got %v", sema.GrantedImmediately, got)
+	}
+
+	if got := sg.ReserveNStatus(nil, 3); got != sema.Impossible {
+		t.Errorf("ReserveNStatus should return %v, got %v", sema.Impossible, got)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if got := sg.ReserveNStatus(ctx.Done(), 2); got != sema.Aborted {
+		t.Errorf("ReserveNStatus should return %v, got %v", sema.Aborted, got)
+	}
+
+	go func() {
+		for sg.PendingCount() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		sg.Free()
+	}()
+	if got := sg.ReserveNStatus(nil, 2); got != sema.GrantedAfterWait {
+		t.Errorf("ReserveNStatus should return %v, got %v", sema.GrantedAfterWait, got)
+	}
+
+	sg.FreeN(2)
+	sg.Wait()
+}
+
+func TestGroupReserveNWithCleanup(t *testing.T) {
+	t.Parallel()
+
+	sg := sema.NewGroup(1)
+
+	aborts := 0
+	onAbort := func() {
+		if pending := sg.PendingCount(); pending != 0 {
+			t.Errorf("Group pending count should be 0 on abort, got %d", pending)
+		}
+		aborts++
+	}
+
+	if !sg.ReserveNWithCleanup(nil, 1, onAbort) {
+		t.Fatalf("ReserveNWithCleanup should succeed")
+	}
+	if sg.ReserveNWithCleanup(nil, 2, onAbort) {
+		t.Fatalf("ReserveNWithCleanup should fail")
+	}
+	if aborts != 0 {
+		t.Errorf("onAbort shouldn't be called without an abort, got %d calls", aborts)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if sg.ReserveNWithCleanup(ctx.Done(), 1, onAbort) {
+		t.Fatalf("ReserveNWithCleanup should fail")
+	}
+	if aborts != 1 {
+		t.Errorf("onAbort should be called once, got %d calls", aborts)
+	}
+
+	sg.Free()
+}
+
+func TestGroupReserveNWatch(t *testing.T) {
+	t.Parallel()
+
+	sg := sema.NewGroup(1)
+	sg.Reserve()
+
+	// block another call ahead of the watched one.
+	aheadDone := make(chan struct{})
+	go func() {
+		sg.Reserve()
+		close(aheadDone)
+	}()
+	for sg.PendingCount() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	positions := make(chan int, 10)
+	reserved := make(chan bool)
+	go func() {
+		reserved <- sg.ReserveNWatch(context.Background(), 1, func(pos int) {
+			positions <- pos
+		})
+	}()
+
+	if pos := <-positions; pos != 1 {
+		t.Errorf("the initial position should be 1, got %d", pos)
+	}
+
+	// wake up the blocked calls, one at a time, in whatever order.
+	watchedOK := false
+	for range 2 {
+		sg.Free()
+		select {
+		case <-aheadDone:
+			aheadDone = nil
+		case watchedOK = <-reserved:
+		}
+	}
+	sg.Free()
+
+	if !watchedOK {
+		t.Fatalf("ReserveNWatch should succeed")
+	}
+
+	close(positions)
+	for pos := range positions {
+		if pos != 0 {
+			t.Errorf("the later positions should be 0, got %d", pos)
+		}
+	}
+}
+
+func TestGroupReserveNIf(t *testing.T) {
+	t.Parallel()
+
+	sg := sema.NewGroup(1)
+
+	// the cond isn't called if the call doesn't block.
+	if !sg.ReserveNIf(nil, 1, func() bool { return false }) {
+		t.Fatalf("ReserveNIf should succeed without blocking")
+	}
+
+	for _, want := range []bool{false, true} {
+		var calls atomic.Int32
+		reserved := make(chan bool)
+		go func() {
+			reserved <- sg.ReserveNIf(nil, 1, func() bool {
+				calls.Add(1)
+				return want
+			})
+		}()
+		for sg.PendingCount() != 1 {
+			time.Sleep(time.Millisecond)
+		}
+
+		// the cond is only called once there's room.
+		if n := calls.Load(); n != 0 {
+			t.Fatalf("cond shouldn't be called while blocked, got %d calls", n)
+		}
+
+		sg.Free()
+		if got := <-reserved; got != want {
+			t.Fatalf("ReserveNIf should return %v, got %v", want, got)
+		}
+		if n := calls.Load(); n != 1 {
+			t.Fatalf("cond should be called once, got %d calls", n)
+		}
+		if pending := sg.PendingCount(); pending != 0 {
+			t.Fatalf("Group pending count should be 0, got %d", pending)
+		}
+
+		if !want {
+			// the room is left for the next calls.
+			sg.Reserve()
+		}
+	}
+
+	sg.Free()
+	sg.Wait()
+}
+
+func TestGroupReserveNTimer(t *testing.T) {
+	t.Parallel()
+
+	sg := sema.NewGroup(2)
+	sg.Reserve()
+
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+	if !sg.ReserveNTimer(timer, 1) {
+		t.Fatalf("ReserveNTimer should succeed while there's room")
+	}
+
+	timer = time.NewTimer(10 * time.Millisecond)
+	if sg.ReserveNTimer(timer, 2) {
+		t.Fatalf("ReserveNTimer should fail once the timer fires")
+	}
+	if active := sg.ActiveCount(); active != 2 {
+		t.Errorf("Group active count should be 2, got %d", active)
+	}
+	if pending := sg.PendingCount(); pending != 0 {
+		t.Errorf("Group pending count should be 0, got %d", pending)
+	}
+
+	sg.FreeN(2)
+	sg.Wait()
+
+	defer func() {
+		if err := recover(); err != "sema.Group: nil timer" {
+			t.Fatalf("Unexpected panic: %#v", err)
+		}
+	}()
+	sg.ReserveNTimer(nil, 1)
+	t.Fatal("Should panic")
+}
+
+func TestGroupSetEnforce(t *testing.T) {
+	t.Parallel()
+
+	sg := sema.NewGroup(2)
+	sg.SetEnforce(false)
+
+	for range 3 {
+		if !sg.ReserveN(nil, 1) {
+			t.Fatalf("ReserveN should succeed while not enforced")
+		}
+	}
+	if !sg.TryReserveN(3) {
+		t.Fatalf("TryReserveN should succeed while not enforced")
+	}
+
+	if active := sg.ActiveCount(); active != 6 {
+		t.Errorf("Group active count should be 6, got %d", active)
+	}
+	if blocked := sg.WouldHaveBlockedCount(); blocked != 2 {
+		t.Errorf("Group would have blocked count should be 2, got %d", blocked)
+	}
+
+	// once enforced, no room is available until active goes within size.
+	sg.SetEnforce(true)
+	if sg.TryReserveN(1) {
+		t.Fatalf("TryReserveN should fail while enforced")
+	}
+
+	waitChan := sg.WaitChan()
+	sg.FreeN(6)
+	<-waitChan
+
+	if !sg.TryReserveN(2) {
+		t.Fatalf("TryReserveN should succeed while there's room")
+	}
+	sg.FreeN(2)
+}
+
+// TestGroupFreeAbortRace races a Free call with the abort of the only blocked
+// ReserveN call, and checks that the counters end up exactly matching the
+// result of the ReserveN call.
+func TestGroupFreeAbortRace(t *testing.T) {
+	t.Parallel()
+
+	for range 1000 {
+		sg := sema.NewGroup(1)
+		sg.Reserve()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		reserved := make(chan bool)
+		go func() {
+			reserved <- sg.ReserveN(ctx.Done(), 1)
+		}()
+		for sg.PendingCount() == 0 {
+			runtime.Gosched()
+		}
+
+		start := make(chan struct{})
+		wg := sync.WaitGroup{}
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			<-start
+			sg.Free()
+		}()
+		go func() {
+			defer wg.Done()
+			<-start
+			cancel()
+		}()
+		close(start)
+		wg.Wait()
+
+		wantActive := 0
+		if <-reserved {
+			wantActive = 1
+		}
+		if active := sg.ActiveCount(); active != wantActive {
+			t.Fatalf("Group active count should be %d, got %d", wantActive, active)
+		}
+		if pending := sg.PendingCount(); pending != 0 {
+			t.Fatalf("Group pending count should be 0, got %d", pending)
+		}
+
+		// no spurious room was made by the race.
+		if wantActive == 1 && sg.TryReserveN(1) {
+			t.Fatalf("TryReserveN should fail on a full Group")
+		}
+
+		if wantActive == 1 {
+			sg.Free()
+		}
+		sg.Wait()
+	}
+}
+
+func TestGroupAcquireContext(t *testing.T) {
+	t.Parallel()
+
+	sg := sema.NewGroup(2)
+
+	// release cancels the returned ctx and frees exactly once.
+	ctx, release, err := sg.AcquireContext(context.Background(), 2)
+	if err != nil {
+		t.Fatalf("AcquireContext should succeed, got: %v", err)
+	}
+	if ctx.Err() != nil {
+		t.Fatalf("the returned ctx shouldn't be done before release")
+	}
+	release()
+	release()
+	if ctx.Err() == nil {
+		t.Fatalf("the returned ctx should be done after release")
+	}
+	if active := sg.ActiveCount(); active != 0 {
+		t.Fatalf("Group active count should be 0, got %d", active)
+	}
+
+	// canceling parent cancels the returned ctx and frees.
+	parent, cancel := context.WithCancel(context.Background())
+	ctx, release, err = sg.AcquireContext(parent, 1)
+	if err != nil {
+		t.Fatalf("AcquireContext should succeed, got: %v", err)
+	}
+	cancel()
+	<-ctx.Done()
+	sg.Wait()
+	release()
+	if active := sg.ActiveCount(); active != 0 {
+		t.Fatalf("Group active count should be 0, got %d", active)
+	}
+
+	// an aborted reservation returns the parent error.
+	sg.ReserveN(nil, 2)
+	defer sg.FreeN(2)
+	ctx, release, err = sg.AcquireContext(parent, 1)
+	if err != context.Canceled || ctx != nil || release != nil {
+		t.Fatalf("AcquireContext should fail with context.Canceled, got: %v", err)
+	}
+}
+
+func TestGroupTryReserveNOrShortfall(t *testing.T) {
+	t.Parallel()
+
+	sg := sema.NewGroup(5)
+
+	if ok, shortfall := sg.TryReserveNOrShortfall(3); !ok || shortfall != 0 {
+		t.Fatalf("TryReserveNOrShortfall(3) should succeed, got: %v, %d", ok, shortfall)
+	}
+	if ok, shortfall := sg.TryReserveNOrShortfall(4); ok || shortfall != 2 {
+		t.Fatalf("TryReserveNOrShortfall(4) should be short by 2, got: %v, %d", ok, shortfall)
+	}
+	if ok, shortfall := sg.TryReserveNOrShortfall(7); ok || shortfall != 5 {
+		t.Fatalf("TryReserveNOrShortfall(7) should be short by 5, got: %v, %d", ok, shortfall)
+	}
+
+	// with a blocked call, nothing is available.
+	go sg.ReserveN(nil, 3)
+	for sg.PendingCount() == 0 {
+		runtime.Gosched()
+	}
+	if ok, shortfall := sg.TryReserveNOrShortfall(1); ok || shortfall != 1 {
+		t.Fatalf("TryReserveNOrShortfall(1) should be short by 1, got: %v, %d", ok, shortfall)
+	}
+
+	sg.FreeN(3)
+	for sg.ActiveCount() != 3 {
+		runtime.Gosched()
+	}
+	sg.FreeN(3)
+	sg.Wait()
+}
+
+func TestGroupReserveNStatusEx(t *testing.T) {
+	// not parallel, as it sets a package-wide test hook.
+
+	sg := sema.NewGroup(2)
+
+	if outcome, source := sg.ReserveNStatusEx(nil, 2); outcome != sema.GrantedImmediately || source != sema.SourceNone {
+		t.Fatalf("ReserveNStatusEx should be granted immediately, got: %v, %v", outcome, source)
+	}
+
+	// a Free call hands the room to the blocked call.
+	type result struct {
+		outcome sema.Outcome
+		source  sema.GrantSource
+	}
+	results := make(chan result)
+	reserve := func() {
+		outcome, source := sg.ReserveNStatusEx(nil, 1)
+		results <- result{outcome, source}
+	}
+
+	go reserve()
+	for sg.PendingCount() == 0 {
+		runtime.Gosched()
+	}
+	sg.Free()
+	if r := <-results; r.outcome != sema.GrantedAfterWait || r.source != sema.SourceHandoff {
+		t.Fatalf("ReserveNStatusEx should be granted by a handoff, got: %v, %v", r.outcome, r.source)
+	}
+
+	// the counter changes right before the woken up call takes the room,
+	// so it finds the room on a re-check.
+	go reserve()
+	for sg.PendingCount() == 0 {
+		runtime.Gosched()
+	}
+	once := sync.Once{}
+	restore := sema.SetTestHookBeforeGrant(func() {
+		once.Do(func() {
+			sg.TryAdmit(1)
+			sg.ReleaseSlots(2)
+		})
+	})
+	sg.Free()
+	r := <-results
+	restore()
+	if r.outcome != sema.GrantedAfterWait || r.source != sema.SourceRecheck {
+		t.Fatalf("ReserveNStatusEx should be granted by a re-check, got: %v, %v", r.outcome, r.source)
+	}
+
+	sg.Free()
+	sg.Wait()
+}
+
+func TestGroupHugeN(t *testing.T) {
+	t.Parallel()
+
+	if strconv.IntSize == 32 {
+		t.Skip("an int can't exceed math.MaxInt32 on this platform")
+	}
+	maxN := int64(math.MaxInt32)
+	hugeN := int(maxN + 1)
+
+	for _, tc := range []struct {
+		name string
+		call func(sg *sema.Group)
+		want string
+	}{
+		{"ReserveN", func(sg *sema.Group) { sg.ReserveN(nil, hugeN) }, "sema.Group: group reserve N value out of range"},
+		{"TryReserveN", func(sg *sema.Group) { sg.TryReserveN(hugeN) }, "sema.Group: group reserve N value out of range"},
+		{"FreeN", func(sg *sema.Group) { sg.FreeN(hugeN) }, "sema.Group: group free N value out of range"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			sg := sema.NewGroup(0)
+			func() {
+				defer func() {
+					if err := recover(); err != tc.want {
+						t.Fatalf("Unexpected panic: %#v", err)
+					}
+				}()
+				tc.call(sg)
+				t.Fatal("Should panic")
+			}()
+
+			// the counter wasn't touched by the call.
+			if active := sg.ActiveCount(); active != 0 {
+				t.Fatalf("Group active count should be 0, got %d", active)
+			}
+		})
+	}
+}
+
+func TestGroupReserveNOverSize(t *testing.T) {
+	t.Parallel()
+
+	const want = "sema.Group: reserve N exceeds group size"
+
+	for _, tc := range []struct {
+		name string
+		call func(sg *sema.Group)
+	}{
+		{"ReserveN", func(sg *sema.Group) { sg.ReserveN(nil, 3) }},
+		{"ReserveN with doneChan", func(sg *sema.Group) { sg.ReserveN(make(chan struct{}), 3) }},
+		{"TryReserveN", func(sg *sema.Group) { sg.TryReserveN(3) }},
+		{"ReserveNNoQueue", func(sg *sema.Group) { sg.ReserveNNoQueue(nil, 3) }},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			sg := sema.NewGroup(2)
+			func() {
+				defer func() {
+					if err := recover(); err != want {
+						t.Fatalf("Unexpected panic: %#v", err)
+					}
+				}()
+				tc.call(sg)
+				t.Fatal("Should panic")
+			}()
+
+			// the counter wasn't touched by the call.
+			if active, pending := sg.ActiveCount(), sg.PendingCount(); active != 0 || pending != 0 {
+				t.Fatalf("Group counts should be 0, got %d and %d", active, pending)
+			}
+
+			// the unlimited size is unaffected.
+			unlimited := sema.NewGroup(0)
+			tc.call(unlimited)
+			if active := unlimited.ActiveCount(); active != 3 {
+				t.Fatalf("Group active count should be 3, got %d", active)
+			}
+		})
+	}
+
+	// the status and ctx variants still report it.
+	sg := sema.NewGroup(2)
+	if outcome := sg.ReserveNStatus(nil, 3); outcome != sema.Impossible {
+		t.Fatalf("ReserveNStatus should be %v, got %v", sema.Impossible, outcome)
+	}
+}
+
+func TestGroupWakeupStats(t *testing.T) {
+	t.Parallel()
+
+	sg := sema.NewGroup(1)
+
+	// the free calls aren't counted until the counting starts.
+	sg.Reserve()
+	sg.Free()
+	if stats := sg.WakeupStats(); stats != (sema.WakeupStats{}) {
+		t.Fatalf("Unexpected wakeup stats before the first call: %+v", stats)
+	}
+
+	// a free with no blocked calls.
+	sg.Reserve()
+	sg.Free()
+	if stats := sg.WakeupStats(); stats != (sema.WakeupStats{FreesWithNoWaiter: 1}) {
+		t.Fatalf("Unexpected wakeup stats: %+v", stats)
+	}
+
+	// a free that hands off to a blocked call.
+	sg.Reserve()
+	reserved := make(chan struct{})
+	go func() {
+		sg.Reserve()
+		close(reserved)
+	}()
+	for sg.PendingCount() == 0 {
+		runtime.Gosched()
+	}
+	sg.Free()
+	<-reserved
+	sg.Free()
+
+	want := sema.WakeupStats{FreesWithNoWaiter: 2, FreesThatWokeWaiter: 1}
+	if stats := sg.WakeupStats(); stats != want {
+		t.Fatalf("Unexpected wakeup stats: %+v", stats)
+	}
+}
+
+func TestGroupSetSlowReserveThreshold(t *testing.T) {
+	t.Parallel()
+
+	const hold = 20 * time.Millisecond
+
+	sg := sema.NewGroup(2)
+
+	type slowCall struct {
+		waited time.Duration
+		n      int
+	}
+	logged := make(chan slowCall, 2)
+	sg.SetSlowReserveThreshold(hold/2, func(waited time.Duration, n int) {
+		logged <- slowCall{waited, n}
+	})
+
+	// a call granted right away isn't logged.
+	sg.ReserveN(nil, 2)
+
+	time.AfterFunc(hold, func() { sg.FreeN(2) })
+	sg.ReserveN(nil, 2)
+
+	select {
+	case c := <-logged:
+		if c.n != 2 || c.waited < hold/2 || c.waited > 10*time.Second {
+			t.Fatalf("Unexpected slow reservation log: %+v", c)
+		}
+	default:
+		t.Fatalf("the slow reservation should be logged")
+	}
+	if len(logged) != 0 {
+		t.Fatalf("only the slow reservation should be logged")
+	}
+
+	sg.FreeN(2)
+}
+
+// TestGroupWaitBeforeFirstReserve pins the same ordering that
+// [sync.WaitGroup] documents for Add and Wait: a Reserve call that
+// happens-before a Wait call is always waited for, while a Wait call on
+// a zero Group returns right away, even if a Reserve call follows it.
+func TestGroupWaitBeforeFirstReserve(t *testing.T) {
+	t.Parallel()
+
+	for range 1000 {
+		// a Wait call on a fresh Group returns right away.
+		sg := &sema.Group{}
+		sg.Wait()
+
+		// a Reserve call that happens-before the Wait call is waited for.
+		reserved := make(chan struct{})
+		go func() {
+			sg.Reserve()
+			close(reserved)
+		}()
+		<-reserved
+
+		waited := make(chan struct{})
+		go func() {
+			sg.Wait()
+			close(waited)
+		}()
+
+		select {
+		case <-waited:
+			t.Fatalf("Wait should block until the Group reaches zero")
+		default:
+		}
+
+		sg.Free()
+		<-waited
+	}
+}
+
+func TestGroupOnBatchComplete(t *testing.T) {
+	t.Parallel()
+
+	sg := sema.NewGroup(2)
+
+	// already zero, so f is called immediately.
+	called := false
+	sg.OnBatchComplete(func() { called = true })
+	if !called {
+		t.Fatalf("OnBatchComplete should call f immediately on a zero Group")
+	}
+
+	// non-zero, so f is called once the batch drains, and only once.
+	var calls atomic.Int32
+	done := make(chan struct{})
+	sg.ReserveN(nil, 2)
+	sg.OnBatchComplete(func() {
+		calls.Add(1)
+		close(done)
+	})
+
+	sg.Free()
+	select {
+	case <-done:
+		t.Fatalf("OnBatchComplete shouldn't call f before the Group drains")
+	default:
+	}
+	sg.Free()
+	<-done
+
+	// another batch doesn't call it again.
+	sg.Reserve()
+	sg.Free()
+	sg.Wait()
+	if c := calls.Load(); c != 1 {
+		t.Fatalf("OnBatchComplete should call f once, got %d", c)
+	}
+}
+
+func TestGroupSetMaxPendingWeight(t *testing.T) {
+	t.Parallel()
+
+	sg := sema.NewGroup(4)
+	sg.SetMaxPendingWeight(3)
+	sg.ReserveN(nil, 4)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	reserved := make(chan bool)
+	go func() {
+		reserved <- sg.ReserveN(ctx.Done(), 2)
+	}()
+	for sg.PendingCount() != 2 {
+		runtime.Gosched()
+	}
+
+	// 2 pending + 2 would exceed the max pending weight of 3.
+	if outcome := sg.ReserveNStatus(ctx.Done(), 2); outcome != sema.Rejected {
+		t.Fatalf("ReserveNStatus should be rejected, got: %v", outcome)
+	}
+	if pending := sg.PendingCount(); pending != 2 {
+		t.Fatalf("Group pending count should be 2, got %d", pending)
+	}
+
+	// 2 pending + 1 is within the max pending weight.
+	go func() {
+		reserved <- sg.ReserveN(ctx.Done(), 1)
+	}()
+	for sg.PendingCount() != 3 {
+		runtime.Gosched()
+	}
+
+	// the pending weight is reconciled on abort, and on grant.
+	cancel()
+	<-reserved
+	<-reserved
+	if pending := sg.PendingCount(); pending != 0 {
+		t.Fatalf("Group pending count should be 0, got %d", pending)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		reserved <- sg.ReserveN(ctx.Done(), 3)
+	}()
+	for sg.PendingCount() != 3 {
+		runtime.Gosched()
+	}
+	sg.FreeN(4)
+	if !<-reserved {
+		t.Fatalf("ReserveN should be granted")
+	}
+	if stats := sg.Stats(); stats.Active != 3 || stats.Pending != 0 {
+		t.Fatalf("Unexpected Group stats: %+v", stats)
+	}
+	sg.FreeN(3)
+}
+
+func TestGroupFreeOnDone(t *testing.T) {
+	t.Parallel()
+
+	sg := sema.NewGroup(2)
+
+	// stop on the normal path frees, and cancels the free on ctx.
+	ctx, cancel := context.WithCancel(context.Background())
+	sg.ReserveN(nil, 2)
+	stop := sg.FreeOnDone(ctx, 2)
+	stop()
+	stop()
+	if active := sg.ActiveCount(); active != 0 {
+		t.Fatalf("Group active count should be 0, got %d", active)
+	}
+	cancel()
+
+	// ctx done frees, and stop after that doesn't free again.
+	ctx, cancel = context.WithCancel(context.Background())
+	sg.ReserveN(nil, 2)
+	stop = sg.FreeOnDone(ctx, 2)
+	cancel()
+	sg.Wait()
+	stop()
+	if active := sg.ActiveCount(); active != 0 {
+		t.Fatalf("Group active count should be 0, got %d", active)
+	}
+}
+
+func TestGroupReserveOrWait(t *testing.T) {
+	t.Parallel()
+
+	sg := sema.NewGroup(2)
+
+	// room for n, so it's reserved.
+	if reserved, err := sg.ReserveOrWait(context.Background(), 2); !reserved || err != nil {
+		t.Fatalf("ReserveOrWait should reserve, got: %v, %v", reserved, err)
+	}
+
+	// n greater than the size, so it waits for the drain.
+	drained := make(chan error)
+	go func() {
+		reserved, err := sg.ReserveOrWait(context.Background(), 3)
+		if reserved {
+			err = errors.New("reserved")
+		}
+		drained <- err
+	}()
+	sg.Free()
+	select {
+	case err := <-drained:
+		t.Fatalf("ReserveOrWait shouldn't return before the drain, got: %v", err)
+	default:
+	}
+	sg.Free()
+	if err := <-drained; err != nil {
+		t.Fatalf("ReserveOrWait should return on the drain, got: %v", err)
+	}
+
+	// ctx done before the drain.
+	sg.Reserve()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if reserved, err := sg.ReserveOrWait(ctx, 3); reserved || err != context.Canceled {
+		t.Fatalf("ReserveOrWait should fail with the ctx error, got: %v, %v", reserved, err)
+	}
+	sg.Free()
+}
+
+func TestGroupReserveNContext(t *testing.T) {
+	t.Parallel()
+
+	sg := sema.NewGroup(2)
+
+	if err := sg.ReserveNContext(context.Background(), 2); err != nil {
+		t.Fatalf("ReserveNContext should succeed, got: %v", err)
+	}
+
+	// n greater than the size fails right away, even with a ctx that's
+	// never done.
+	if err := sg.ReserveNContext(context.Background(), 3); !errors.Is(err, sema.ErrReserveTooLarge) {
+		t.Fatalf("ReserveNContext should fail with %v, got: %v", sema.ErrReserveTooLarge, err)
+	}
+
+	// the ctx error is returned once it's done while blocked.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := sg.ReserveContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("ReserveContext should fail with %v, got: %v", context.DeadlineExceeded, err)
+	}
+	if pending := sg.PendingCount(); pending != 0 {
+		t.Fatalf("Group pending count should be 0, got %d", pending)
+	}
+
+	sg.FreeN(2)
+	if err := sg.ReserveContext(context.Background()); err != nil {
+		t.Fatalf("ReserveContext should succeed, got: %v", err)
+	}
+	sg.Free()
+	sg.Wait()
+}
+
+func TestGroupReserveNCtxInfo(t *testing.T) {
+	t.Parallel()
+
+	sg := sema.NewGroup(3)
+
+	if ok, available, err := sg.ReserveNCtxInfo(context.Background(), 2); !ok || available != 0 || err != nil {
+		t.Fatalf("ReserveNCtxInfo should succeed with 0 available, got: %v, %d, %v", ok, available, err)
+	}
+
+	// n greater than the size fails right away, reporting the room left.
+	if ok, available, err := sg.ReserveNCtxInfo(context.Background(), 4); ok || available != 1 ||
+		!errors.Is(err, sema.ErrReserveTooLarge) {
+		t.Fatalf("ReserveNCtxInfo should fail with 1 available and %v, got: %v, %d, %v",
+			sema.ErrReserveTooLarge, ok, available, err)
+	}
+
+	// the room at the abort point is reported once ctx is done while blocked.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if ok, available, err := sg.ReserveNCtxInfo(ctx, 2); ok || available != 1 ||
+		!errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("ReserveNCtxInfo should fail with 1 available and %v, got: %v, %d, %v",
+			context.DeadlineExceeded, ok, available, err)
+	}
+	if pending := sg.PendingCount(); pending != 0 {
+		t.Fatalf("Group pending count should be 0, got %d", pending)
+	}
+
+	// a call that would exceed the max pending weight is rejected, if it
+	// can be aborted.
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	sg.SetMaxPendingWeight(1)
+	if ok, available, err := sg.ReserveNCtxInfo(ctx, 2); ok || available != 1 ||
+		!errors.Is(err, sema.ErrMaxPendingWeight) {
+		t.Fatalf("ReserveNCtxInfo should fail with 1 available and %v, got: %v, %d, %v",
+			sema.ErrMaxPendingWeight, ok, available, err)
+	}
+	sg.SetMaxPendingWeight(0)
+
+	// a call that isn't admitted by the gate fails right away.
+	sg.SetAdmissionGate(func() bool { return false })
+	if ok, available, err := sg.ReserveNCtxInfo(context.Background(), 1); ok || available != 1 ||
+		!errors.Is(err, sema.ErrNotAdmitted) {
+		t.Fatalf("ReserveNCtxInfo should fail with 1 available and %v, got: %v, %d, %v",
+			sema.ErrNotAdmitted, ok, available, err)
+	}
+	sg.SetAdmissionGate(nil)
+
+	sg.FreeN(2)
+	sg.Wait()
+}
+
+func TestGroupContentionRatio(t *testing.T) {
+	t.Parallel()
+
+	const reservations = 256
+
+	sg := sema.NewGroup(1)
+	if ratio := sg.ContentionRatio(); ratio != 0 {
+		t.Fatalf("ContentionRatio should be 0 without reservations, got %v", ratio)
+	}
+
+	saturated := func() {
+		for range reservations {
+			sg.Reserve()
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				sg.Reserve()
+				sg.Free()
+			}()
+			for sg.PendingCount() != 1 {
+				runtime.Gosched()
+			}
+			sg.Free()
+			<-done
+		}
+	}
+	idle := func() {
+		for range 2 * reservations {
+			sg.Reserve()
+			sg.Free()
+		}
+	}
+
+	for range 2 {
+		saturated()
+		// half of the saturated reservations blocked.
+		if ratio := sg.ContentionRatio(); ratio != 0.5 {
+			t.Fatalf("ContentionRatio should be 0.5 after a saturated phase, got %v", ratio)
+		}
+
+		idle()
+		if ratio := sg.ContentionRatio(); ratio != 0 {
+			t.Fatalf("ContentionRatio should be 0 after an idle phase, got %v", ratio)
+		}
+	}
+}
+
+func TestGroupUpgrade(t *testing.T) {
+	t.Parallel()
+
+	sg := sema.NewGroup(3)
+	sg.Reserve()
+
+	// another holder takes the rest of the room.
+	sg.ReserveN(nil, 2)
+
+	upgraded := make(chan bool)
+	go func() {
+		upgraded <- sg.Upgrade(1, 2, nil)
+	}()
+	for sg.PendingCount() != 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// the held 1 is kept while the upgrade is blocked.
+	if active := sg.ActiveCount(); active != 3 {
+		t.Fatalf("Group active count should be 3, got %d", active)
+	}
+
+	sg.FreeN(2)
+	if !<-upgraded {
+		t.Fatalf("Upgrade should succeed once the other holder frees")
+	}
+	if active := sg.ActiveCount(); active != 3 {
+		t.Fatalf("Group active count should be 3, got %d", active)
+	}
+
+	// an upgrade beyond the size fails right away, even without a doneChan.
+	if sg.Upgrade(3, 1, nil) {
+		t.Fatalf("Upgrade should fail beyond the size")
+	}
+
+	sg.FreeN(3)
+	sg.Wait()
+
+	// an invalid additional panics, even if the sum is beyond the size.
+	defer func() {
+		if err := recover(); err != "sema.Group: invalid group reserve N value" {
+			t.Fatalf("Unexpected panic: %#v", err)
+		}
+	}()
+	sg.Upgrade(20, 0, nil)
+	t.Fatal("Should panic")
+}
+
+func TestGroupDowngrade(t *testing.T) {
+	t.Parallel()
+
+	sg := sema.NewGroup(3)
+	sg.ReserveN(nil, 3)
+
+	reserved := make(chan bool)
+	go func() {
+		reserved <- sg.ReserveN(nil, 2)
+	}()
+	for sg.PendingCount() != 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// the released room admits the blocked call sized to it.
+	if held := sg.Downgrade(3, 2); held != 1 {
+		t.Fatalf("Downgrade should return the remaining 1, got %d", held)
+	}
+	if !<-reserved {
+		t.Fatalf("the blocked call should be admitted by the downgrade")
+	}
+	if active := sg.ActiveCount(); active != 3 {
+		t.Fatalf("Group active count should be 3, got %d", active)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("Downgrade should panic when releasing more than held")
+			}
+		}()
+		sg.Downgrade(1, 2)
+	}()
+
+	sg.FreeN(3)
+	sg.Wait()
+}
+
+func TestGroupWaiterCount(t *testing.T) {
+	t.Parallel()
+
+	const waiters = 5
+
+	sg := sema.NewGroup(1)
+	sg.Reserve()
+
+	wg := sync.WaitGroup{}
+	for range waiters {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sg.Wait()
+		}()
+	}
+
+	// the WaitChan waiters aren't counted.
+	_ = sg.WaitChan()
+
+	for sg.WaiterCount() != waiters {
+		time.Sleep(time.Millisecond)
+	}
+
+	sg.Free()
+	wg.Wait()
+
+	if n := sg.WaiterCount(); n != 0 {
+		t.Fatalf("Group waiter count should be 0, got %d", n)
+	}
+}
+
+func TestGroupOverFreeWithBlockedReserve(t *testing.T) {
+	t.Parallel()
+
+	sg := sema.NewGroup(2)
+	sg.Reserve()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	reserved := make(chan bool)
+	go func() {
+		reserved <- sg.ReserveN(ctx.Done(), 2)
+	}()
+	for sg.PendingCount() != 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	func() {
+		defer func() {
+			if v := recover(); v != "sema.Group: negative group counter" {
+				t.Errorf("over freeing should panic, got %v", v)
+			}
+		}()
+		sg.FreeN(2)
+	}()
+
+	// nothing is updated by the invalid call, so the blocked call isn't
+	// granted the room that was never reserved.
+	if active, pending := sg.ActiveCount(), sg.PendingCount(); active != 1 || pending != 2 {
+		t.Fatalf("Group counts should be unchanged, got %d active and %d pending", active, pending)
+	}
+	select {
+	case <-reserved:
+		t.Fatalf("the blocked call shouldn't be granted by an over free")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	// the Group keeps working after the invalid call.
+	cancel()
+	if <-reserved {
+		t.Fatalf("the blocked call should be aborted")
+	}
+	sg.Free()
+	sg.Wait()
+}
+
+func TestGroupReleaseSlotsOverFree(t *testing.T) {
+	t.Parallel()
+
+	sg := sema.NewGroup(2)
+	sg.Reserve()
+
+	func() {
+		defer func() {
+			if v := recover(); v != "sema.Group: negative group counter" {
+				t.Errorf("over releasing should panic, got %v", v)
+			}
+		}()
+		sg.ReleaseSlots(2)
+	}()
+
+	// nothing is updated by the invalid call.
+	if active := sg.ActiveCount(); active != 1 {
+		t.Fatalf("Group active count should be unchanged, got %d", active)
+	}
+	sg.ReleaseSlots(1)
+	sg.Wait()
+}
+
+func TestGroupReserveNFlag(t *testing.T) {
+	t.Parallel()
+
+	sg := sema.NewGroup(1)
+
+	var cancel atomic.Bool
+	if !sg.ReserveNFlag(&cancel, 1) {
+		t.Fatalf("ReserveNFlag should succeed without blocking")
+	}
+
+	reserved := make(chan bool)
+	go func() {
+		reserved <- sg.ReserveNFlag(&cancel, 1)
+	}()
+	for sg.PendingCount() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// setting the flag mid-block aborts the call promptly.
+	cancel.Store(true)
+	select {
+	case ok := <-reserved:
+		if ok {
+			t.Fatalf("ReserveNFlag should be aborted")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("ReserveNFlag should be aborted promptly")
+	}
+	if pending := sg.PendingCount(); pending != 0 {
+		t.Fatalf("Group pending count should be 0, got %d", pending)
+	}
+
+	// an already set flag aborts right away.
+	sg.Free()
+	if sg.ReserveNFlag(&cancel, 1) {
+		t.Fatalf("ReserveNFlag should fail with a set flag")
+	}
+	sg.Wait()
+}
+
+// run with the race detector, to confirm that the data written before the
+// free calls is visible once the Group is observed as zero.
+func TestGroupHappensBefore(t *testing.T) {
+	t.Parallel()
+
+	const workers = 10
+
+	for _, size := range []int{0, 3} {
+		t.Run("size-"+strconv.Itoa(size), func(t *testing.T) {
+			t.Parallel()
+
+			sg := sema.NewGroup(size)
+
+			// the data written before Free is visible after Wait returns.
+			data := make([]int, workers)
+			for i := range workers {
+				sg.Reserve()
+				go func() {
+					data[i] = i
+					sg.Free()
+				}()
+			}
+			sg.Wait()
+			for i, v := range data {
+				if v != i {
+					t.Fatalf("data[%d] should be %d after Wait, got %d", i, i, v)
+				}
+			}
+
+			// and after the WaitChan is closed, or a zero ActiveCount is
+			// observed, once no more reserve calls are made.
+			for i := range workers {
+				sg.Reserve()
+				go func() {
+					data[i] = -i
+					sg.Free()
+				}()
+			}
+			waitChan := sg.WaitChan()
+			for sg.ActiveCount() != 0 {
+				runtime.Gosched()
+			}
+			for i, v := range data {
+				if v != -i {
+					t.Fatalf("data[%d] should be %d after a zero ActiveCount, got %d", i, -i, v)
+				}
+			}
+			<-waitChan
+		})
+	}
+}
+
+func TestGroupReserveNSaturating(t *testing.T) {
+	t.Parallel()
+
+	const size = 5
+
+	sg := sema.NewGroup(size)
+
+	saturatedChan := make(chan bool)
+	for range 2 * size {
+		go func() {
+			ok, saturated := sg.ReserveNSaturating(nil, 1)
+			if !ok {
+				t.Errorf("ReserveNSaturating should succeed")
+			}
+			saturatedChan <- saturated
+		}()
+	}
+
+	// exactly one call of the burst fills the Group.
+	count := 0
+	for range size {
+		if <-saturatedChan {
+			count++
+		}
+	}
+	if count != 1 {
+		t.Fatalf("exactly 1 call should report saturated, got %d", count)
+	}
+
+	// each refill after a free is a new saturation.
+	for range size {
+		sg.Free()
+		if !<-saturatedChan {
+			t.Fatalf("the call that refills the Group should report saturated")
+		}
+	}
+
+	sg.FreeN(size)
+	sg.Wait()
+
+	if _, saturated := sg.ReserveNSaturating(nil, 1); saturated {
+		t.Fatalf("a call that doesn't fill the Group shouldn't report saturated")
+	}
+	sg.Free()
+}
+
+func TestGroupReservePreempt(t *testing.T) {
+	t.Parallel()
+
+	const normals = 5
+
+	sg := sema.NewGroup(1)
+	sg.Reserve()
+
+	normalChan := make(chan struct{}, normals)
+	for range normals {
+		go func() {
+			sg.Reserve()
+			normalChan <- struct{}{}
+		}()
+	}
+	for sg.PendingCount() != normals {
+		time.Sleep(time.Millisecond)
+	}
+
+	preempted := make(chan bool)
+	go func() {
+		preempted <- sg.ReservePreempt(nil, 1)
+	}()
+	for sg.PendingCount() != normals+1 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	// the preemptive call is granted ahead of the queue of normal calls.
+	sg.Free()
+	if !<-preempted {
+		t.Fatalf("ReservePreempt should succeed")
+	}
+	select {
+	case <-normalChan:
+		t.Fatalf("a normal call shouldn't be granted ahead of the preemptive call")
+	default:
+	}
+
+	for range normals {
+		sg.Free()
+		<-normalChan
+	}
+	sg.Free()
+	sg.Wait()
+}
+
+func TestGroupMaxBlockedPriority(t *testing.T) {
+	t.Parallel()
+
+	sg := sema.NewGroup(1)
+	if p := sg.MaxBlockedPriority(); p != sema.NoBlockedPriority {
+		t.Fatalf("Group max blocked priority should be %d, got %d", sema.NoBlockedPriority, p)
+	}
+	sg.Reserve()
+
+	normalChan := make(chan struct{})
+	go func() {
+		sg.Reserve()
+		close(normalChan)
+	}()
+	for sg.PendingCount() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	if p := sg.MaxBlockedPriority(); p != sema.NormalPriority {
+		t.Fatalf("Group max blocked priority should be %d, got %d", sema.NormalPriority, p)
+	}
+
+	// a blocked preemptive call raises the reported priority, while the
+	// room it needs is still held.
+	preempted := make(chan bool)
+	go func() {
+		preempted <- sg.ReservePreempt(nil, 1)
+	}()
+	for sg.MaxBlockedPriority() != sema.PreemptPriority {
+		time.Sleep(time.Millisecond)
+	}
+
+	sg.Free()
+	if !<-preempted {
+		t.Fatalf("ReservePreempt should succeed")
+	}
+	if p := sg.MaxBlockedPriority(); p != sema.NormalPriority {
+		t.Fatalf("Group max blocked priority should be %d, got %d", sema.NormalPriority, p)
+	}
+
+	sg.Free()
+	<-normalChan
+	if p := sg.MaxBlockedPriority(); p != sema.NoBlockedPriority {
+		t.Fatalf("Group max blocked priority should be %d, got %d", sema.NoBlockedPriority, p)
+	}
+	sg.Free()
+	sg.Wait()
+}
+
+func TestGroupResize(t *testing.T) {
+	t.Parallel()
+
+	sg := sema.NewGroup(2)
+	sg.ReserveN(nil, 2)
+
+	granted := make(chan bool, 3)
+	for range 3 {
+		go func() {
+			granted <- sg.ReserveN(nil, 1)
+		}()
+	}
+	for sg.PendingCount() != 3 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// growing wakes up enough blocked calls to fill the new headroom.
+	sg.Resize(4)
+	if size := sg.Size(); size != 4 {
+		t.Fatalf("Group size should be 4, got %d", size)
+	}
+	for range 2 {
+		if !<-granted {
+			t.Fatalf("ReserveN should succeed once the size grows")
+		}
+	}
+	select {
+	case <-granted:
+		t.Fatalf("ReserveN shouldn't succeed beyond the new size")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	// shrinking doesn't evict the active reservations, but blocks the new
+	// ones until there's room within the new size.
+	sg.Resize(2)
+	if active := sg.ActiveCount(); active != 4 {
+		t.Fatalf("Group active count should be 4, got %d", active)
+	}
+	sg.FreeN(2)
+	if sg.TryReserveN(1) {
+		t.Fatalf("TryReserveN should fail while the active count is at the new size")
+	}
+	sg.Free()
+	if !<-granted {
+		t.Fatalf("ReserveN should succeed once there's room within the new size")
+	}
+	sg.FreeN(2)
+	sg.Wait()
+
+	// a blocked call whose n exceeds the shrunk size gives up once woken up.
+	sg.ReserveN(nil, 2)
+	outcome := make(chan sema.Outcome)
+	go func() {
+		outcome <- sg.ReserveNStatus(nil, 2)
+	}()
+	for sg.PendingCount() != 2 {
+		time.Sleep(time.Millisecond)
+	}
+	sg.Resize(1)
+	sg.Free()
+	if o := <-outcome; o != sema.Impossible {
+		t.Fatalf("ReserveNStatus should be %v, got %v", sema.Impossible, o)
+	}
+	if pending := sg.PendingCount(); pending != 0 {
+		t.Fatalf("Group pending count should be 0, got %d", pending)
+	}
+	sg.Free()
+	sg.Wait()
+
+	// once resized, a new call for an n beyond the size fails like the
+	// stranded ones, rather than panicking.
+	if sg.ReserveN(nil, 2) {
+		t.Fatalf("ReserveN should fail beyond the size of a resized group")
+	}
+	if sg.TryReserveN(2) {
+		t.Fatalf("TryReserveN should fail beyond the size of a resized group")
+	}
+	if err := sg.ReserveNContext(context.Background(), 2); !errors.Is(err, sema.ErrReserveTooLarge) {
+		t.Fatalf("ReserveNContext should fail with %v, got %v", sema.ErrReserveTooLarge, err)
+	}
+	sg.AsWaitGroup().Add(2)
+	if active, pending := sg.ActiveCount(), sg.PendingCount(); active != 0 || pending != 0 {
+		t.Fatalf("Group counts should be 0, got %d and %d", active, pending)
+	}
+}
+
+func TestGroupResizeStranded(t *testing.T) {
+	t.Parallel()
+
+	sg := sema.NewGroup(3)
+	sg.ReserveN(nil, 3)
+
+	// the stranded call passes its wakeup on to the one that can use it.
+	stranded := make(chan bool)
+	go func() {
+		stranded <- sg.ReserveN(nil, 3)
+	}()
+	for sg.PendingCount() != 3 {
+		time.Sleep(time.Millisecond)
+	}
+	small := make(chan bool)
+	go func() {
+		small <- sg.ReserveN(nil, 1)
+	}()
+	for sg.PendingCount() != 4 {
+		time.Sleep(time.Millisecond)
+	}
+
+	sg.Resize(1)
+	sg.FreeN(3)
+	if <-stranded {
+		t.Fatalf("ReserveN should fail once the size is shrunk below its n")
+	}
+	if !<-small {
+		t.Fatalf("ReserveN should succeed within the new size")
+	}
+	sg.Free()
+	sg.Wait()
+}
+
+func TestGroupResizeConcurrent(t *testing.T) {
+	t.Parallel()
+
+	const (
+		workers = 8
+		iters   = 2000
+		maxSize = 4
+	)
+
+	sg := sema.NewGroup(maxSize)
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range iters {
+				// only n within the min size, so no call is stranded.
+				if !sg.ReserveN(nil, 1) {
+					t.Errorf("ReserveN should succeed")
+					return
+				}
+				if active := sg.ActiveCount(); active > maxSize {
+					t.Errorf("Group active count %d exceeds the max size", active)
+				}
+				runtime.Gosched()
+				sg.Free()
+			}
+		}()
+	}
+
+	// the calls of n up to the max size might be stranded by a shrink, or
+	// fail right away, without panicking, but they never block the others.
+	for range workers / 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range iters / 4 {
+				if sg.ReserveN(nil, maxSize) {
+					runtime.Gosched()
+					sg.FreeN(maxSize)
+				}
+			}
+		}()
+	}
+
+	// the resizes race with the reserve and free calls, and with the Wait
+	// calls on the wait channel generations.
+	stop := make(chan struct{})
+	var bg sync.WaitGroup
+	bg.Add(2)
+	go func() {
+		defer bg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			sg.Resize(i%maxSize + 1)
+			runtime.Gosched()
+		}
+	}()
+	go func() {
+		defer bg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-sg.WaitChan():
+				runtime.Gosched()
+			}
+		}
+	}()
+
+	wg.Wait()
+	close(stop)
+	bg.Wait()
+
+	if active, pending := sg.ActiveCount(), sg.PendingCount(); active != 0 || pending != 0 {
+		t.Fatalf("Group counts should be 0, got %d and %d", active, pending)
+	}
+	sg.Wait()
+}
+
+func TestGroupResizePanic(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name string
+		size int
+		new  int
+		want string
+	}{
+		{"zero", 2, 0, "sema.Group: invalid group resize value"},
+		{"negative", 2, -1, "sema.Group: invalid group resize value"},
+		{"unlimited", 0, 2, "sema.Group: resize of an unlimited group"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			sg := sema.NewGroup(tc.size)
+			defer func() {
+				if err := recover(); err != tc.want {
+					t.Fatalf("Unexpected panic: %#v", err)
+				}
+			}()
+			sg.Resize(tc.new)
+			t.Fatal("Should panic")
+		})
+	}
+}
+
+func TestGroupSetSizeConcurrentWait(t *testing.T) {
+	t.Parallel()
+
+	const (
+		rounds  = 100
+		waiters = 8
+	)
+
+	for round := range rounds {
+		var sg sema.Group
+
+		// the Wait calls on the zero Group race with its initialization,
+		// and must neither block nor observe it half done.
+		var wg sync.WaitGroup
+		for range waiters {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				sg.Wait()
+				<-sg.WaitChan()
+			}()
+		}
+		sg.SetSize(2)
+		wg.Wait()
+
+		// the wait channel generation still works after the initialization.
+		sg.ReserveN(nil, 2)
+		waitChan := sg.WaitChan()
+		for range waiters {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				sg.Wait()
+			}()
+		}
+		select {
+		case <-waitChan:
+			t.Fatalf("round %d: WaitChan closed before the Group reached zero", round)
+		default:
+		}
+		sg.FreeN(2)
+		wg.Wait()
+		<-waitChan
+
+		if size := sg.Size(); size != 2 {
+			t.Fatalf("round %d: Group size should be 2, got %d", round, size)
+		}
+	}
+}
+
+func TestGroupWokenReserveRewaits(t *testing.T) {
+	t.Parallel()
+
+	const size = 4
+
+	t.Run("partial frees", func(t *testing.T) {
+		t.Parallel()
+
+		sg := sema.NewGroup(size)
+		sg.ReserveN(nil, size)
+
+		granted := make(chan struct{})
+		go func() {
+			sg.ReserveN(nil, size)
+			close(granted)
+		}()
+		for sg.PendingCount() != size {
+			time.Sleep(time.Millisecond)
+		}
+
+		// each partial free wakes up the blocked call, which still needs
+		// more, so it waits again, for a free that's still due.
+		for range size - 1 {
+			sg.Free()
+			time.Sleep(time.Millisecond)
+			select {
+			case <-granted:
+				t.Fatalf("ReserveN should be blocked until all room is freed")
+			default:
+			}
+		}
+		sg.Free()
+
+		select {
+		case <-granted:
+		case <-time.After(10 * time.Second):
+			t.Fatalf("ReserveN missed the wakeup of the last free")
+		}
+		sg.FreeN(size)
+		sg.Wait()
+	})
+
+	t.Run("mixed n", func(t *testing.T) {
+		t.Parallel()
+
+		const (
+			workers = 8
+			iters   = 2000
+		)
+
+		sg := sema.NewGroup(size)
+		done := make(chan struct{})
+		var wg sync.WaitGroup
+		for w := range workers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range iters {
+					n := (w+i)%size + 1
+					sg.ReserveN(nil, n)
+					runtime.Gosched()
+					sg.FreeN(n)
+				}
+			}()
+		}
+		go func() {
+			wg.Wait()
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(30 * time.Second):
+			t.Fatalf("blocked calls missed a wakeup, active %d, pending %d",
+				sg.ActiveCount(), sg.PendingCount())
+		}
+		sg.Wait()
+	})
+}
+
+func TestGroupWaitRearm(t *testing.T) {
+	t.Parallel()
+
+	const cycles = 1000
+
+	for _, size := range []int{0, 1} {
+		t.Run("size-"+strconv.Itoa(size), func(t *testing.T) {
+			t.Parallel()
+
+			sg := sema.NewGroup(size)
+			for cycle := range cycles {
+				sg.Reserve()
+
+				// freed is set right before the cycle drains, so a Wait that
+				// returns without it returned prematurely.
+				var freed atomic.Bool
+				waited := make(chan struct{})
+				go func() {
+					defer close(waited)
+					sg.Wait()
+					if !freed.Load() {
+						t.Errorf("cycle %d: Wait returned before the cycle drained", cycle)
+					}
+				}()
+
+				// a WaitChan from the same cycle is matched to it too.
+				waitChan := sg.WaitChan()
+				select {
+				case <-waitChan:
+					t.Fatalf("cycle %d: WaitChan closed before the cycle drained", cycle)
+				default:
+				}
+
+				// give the Wait a chance to return prematurely.
+				if cycle%100 == 0 {
+					time.Sleep(time.Millisecond)
+				} else {
+					runtime.Gosched()
+				}
+
+				freed.Store(true)
+				sg.Free()
+				<-waited
+				<-waitChan
+			}
+		})
+	}
+}
+
+func TestGroupRawCounter(t *testing.T) {
+	t.Parallel()
+
+	sg := sema.NewGroup(2)
+	if pending, active := sg.RawCounter(); pending != 0 || active != 0 {
+		t.Fatalf("RawCounter should be 0, 0, got %d, %d", pending, active)
+	}
+
+	sg.ReserveN(nil, 2)
+	reserved := make(chan bool)
+	go func() {
+		reserved <- sg.ReserveN(nil, 1)
+	}()
+	for sg.PendingCount() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if pending, active := sg.RawCounter(); pending != 1 || active != 2 {
+		t.Fatalf("RawCounter should be 1, 2, got %d, %d", pending, active)
+	}
+
+	sg.Free()
+	<-reserved
+	sg.FreeN(2)
+	sg.Wait()
+}
+
+func TestGroupReserveNNoQueue(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sem := sema.NewGroup(2)
+	tries := []bool{}
+	tries = append(tries, sem.ReserveNNoQueue(ctx.Done(), 1))
+	tries = append(tries, sem.ReserveNNoQueue(ctx.Done(), 2))
+	tries = append(tries, sem.ReserveNNoQueue(ctx.Done(), 1))
+	cancel()
+	sem.FreeN(2)
+	tries = append(tries, sem.ReserveNNoQueue(ctx.Done(), 1))
+	tries = append(tries, sem.ReserveNNoQueue(nil, 1))
+
+	want := []bool{true, false, true, false, true}
+	for i := range tries {
+		if tries[i] != want[i] {
+			t.Errorf("tries[%d]: got %t, want %t", i, tries[i], want[i])
+		}
+	}
+	if pending := sem.PendingCount(); pending != 0 {
+		t.Errorf("Group pending count should be 0, got %d", pending)
+	}
+}
+
+// TestGroupSingleP times out if the contended reserve and free calls can't
+// make progress when running on a single P.
+// Merely returning from the test function indicates success.
+func TestGroupSingleP(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(1))
+
+	n := 4
+	loops := 1000
+	sg := sema.NewGroup(n)
+
+	var wg sync.WaitGroup
+	wg.Add(2 * n)
+	for i := 1; i <= n; i++ {
+		go func() {
+			defer wg.Done()
+			for range loops {
+				sg.ReserveN(nil, i)
+				runtime.Gosched()
+				sg.FreeN(i)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for range loops {
+				ctx, cancel := context.WithCancel(context.Background())
+				go cancel()
+				if sg.ReserveN(ctx.Done(), i) {
+					sg.FreeN(i)
+				}
+				cancel()
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("contended reserve and free calls didn't complete on a single P")
+	}
+
+	sg.Wait()
+}
@@ -0,0 +1,117 @@
+// Copyright 2025 Ahmad Sameh(asmsh)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sema
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// SetLogger sets the logger that the [Group] emits structured debug logs
+// to, on the key events of its slow paths, which are a reserve call that
+// blocks, and how it ends, either granted after the wait, with the waited
+// duration, or aborted, with the reason.
+// Over-free attempts are logged at the error level, right before the
+// [Group] panics on them.
+//
+// The logs of a named [Group], as created by [NewNamedGroup], carry its
+// name as the "group" attribute.
+//
+// A nil logger disables the logging, which is the default, and the calls
+// that don't block are never logged, so they have no logging overhead.
+func (g *Group) SetLogger(l *slog.Logger) {
+	if l != nil && g.name != "" {
+		l = l.With(slog.String("group", g.name))
+	}
+	g.initExt().logger.Store(l)
+}
+
+// logBlocked logs a reserve call of n that's about to block, and returns
+// when it started blocking.
+func (g *Group) logBlocked(l *slog.Logger, n int) time.Time {
+	pending, _ := counterParts(g.counter.Load())
+	l.LogAttrs(context.Background(), slog.LevelDebug, "sema: reservation blocked",
+		slog.Int("n", n),
+		slog.Int("pending", int(pending)),
+	)
+	return g.now()
+}
+
+// logUnblocked logs how a blocked reserve call of n, which started blocking
+// at start, ended.
+func (g *Group) logUnblocked(
+	l *slog.Logger,
+	start time.Time,
+	n int,
+	granted bool,
+	doneChan <-chan struct{},
+	opts *reserveOpts,
+) {
+	waited := g.now().Sub(start)
+	if granted {
+		l.LogAttrs(context.Background(), slog.LevelDebug, "sema: reservation granted after wait",
+			slog.Int("n", n),
+			slog.Duration("waited", waited),
+		)
+		return
+	}
+
+	l.LogAttrs(context.Background(), slog.LevelDebug, "sema: reservation aborted",
+		slog.Int("n", n),
+		slog.Duration("waited", waited),
+		slog.String("reason", abortReason(doneChan, opts)),
+	)
+}
+
+// abortReason returns why a blocked reserve call was aborted, which is
+// inferred after the fact, as the timer's value is already received.
+func abortReason(doneChan <-chan struct{}, opts *reserveOpts) string {
+	// a call stranded by a [Group.Resize] gave up on its own.
+	if opts != nil && opts.stranded {
+		return "resize"
+	}
+
+	select {
+	case <-doneChan:
+		return "done"
+	default:
+	}
+
+	switch {
+	case opts.canceled():
+		return "canceled"
+	case opts != nil && opts.timerChan != nil:
+		return "timer"
+	default:
+		return "cond"
+	}
+}
+
+// logOverFree logs an attempt to free n while only active is held.
+func (g *Group) logOverFree(n int, active int32) {
+	e := g.ext.Load()
+	if e == nil {
+		return
+	}
+	l := e.logger.Load()
+	if l == nil {
+		return
+	}
+	l.LogAttrs(context.Background(), slog.LevelError, "sema: over-free attempt",
+		slog.Int("n", n),
+		slog.Int("active", int(active)),
+	)
+}
@@ -0,0 +1,122 @@
+package sema_test
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asmsh/sema"
+)
+
+func TestGroupSetLogger(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	l := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	sg := sema.NewNamedGroup("uploads", 1)
+	sg.SetLogger(l)
+
+	// the calls that don't block aren't logged.
+	sg.Reserve()
+	if buf.Len() != 0 {
+		t.Fatalf("a call that doesn't block shouldn't be logged, got %q", buf.String())
+	}
+
+	granted := make(chan struct{})
+	go func() {
+		sg.Reserve()
+		close(granted)
+	}()
+	for sg.PendingCount() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	sg.Free()
+	<-granted
+
+	doneChan := make(chan struct{})
+	aborted := make(chan bool)
+	go func() {
+		aborted <- sg.ReserveN(doneChan, 1)
+	}()
+	for sg.PendingCount() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	close(doneChan)
+	if <-aborted {
+		t.Fatalf("ReserveN should be aborted")
+	}
+
+	sg.Free()
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("Free should panic on a zero Group")
+			}
+		}()
+		sg.Free()
+	}()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	want := []string{
+		`level=DEBUG msg="sema: reservation blocked" group=uploads n=1 pending=1`,
+		`level=DEBUG msg="sema: reservation granted after wait" group=uploads n=1 waited=`,
+		`level=DEBUG msg="sema: reservation blocked" group=uploads n=1 pending=1`,
+		`level=DEBUG msg="sema: reservation aborted" group=uploads n=1 waited=`,
+		`level=ERROR msg="sema: over-free attempt" group=uploads n=1 active=0`,
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("logged %d lines, want %d:\n%s", len(lines), len(want), buf.String())
+	}
+	for i, line := range lines {
+		if !strings.Contains(line, want[i]) {
+			t.Fatalf("line %d should contain %q, got %q", i, want[i], line)
+		}
+	}
+	if !strings.HasSuffix(lines[3], "reason=done") {
+		t.Fatalf("aborted line should have the done reason, got %q", lines[3])
+	}
+
+	// a nil logger disables the logging.
+	buf.Reset()
+	sg.SetLogger(nil)
+	sg.Reserve()
+	go sg.Free()
+	sg.Reserve()
+	sg.Free()
+	if buf.Len() != 0 {
+		t.Fatalf("a nil logger shouldn't log, got %q", buf.String())
+	}
+}
+
+func TestGroupSetLoggerResize(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	l := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	sg := sema.NewGroup(2)
+	sg.SetLogger(l)
+	sg.ReserveN(nil, 2)
+
+	stranded := make(chan bool)
+	go func() {
+		stranded <- sg.ReserveN(make(chan struct{}), 2)
+	}()
+	for sg.PendingCount() != 2 {
+		time.Sleep(time.Millisecond)
+	}
+	sg.Resize(1)
+	sg.Free()
+	if <-stranded {
+		t.Fatalf("ReserveN should fail once the size is shrunk below its n")
+	}
+	sg.Free()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if last := lines[len(lines)-1]; !strings.HasSuffix(last, "reason=resize") {
+		t.Fatalf("aborted line should have the resize reason, got %q", last)
+	}
+}
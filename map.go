@@ -0,0 +1,82 @@
+// Copyright 2025 Ahmad Sameh(asmsh)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sema
+
+import (
+	"context"
+	"sync"
+)
+
+// Map calls fn for each of the items concurrently, with the concurrency
+// limited by g, and returns the results in the same order as the items.
+//
+// Each fn call reserves 1 from g, exactly like [Group.ReserveN] with
+// ctx.Done() as the doneChan, before it's started in a new goroutine, and
+// frees it once it returns.
+//
+// If any fn call returns an error, the ctx passed to the other calls is
+// canceled, no more calls are started, and Map returns the first error,
+// after all the started calls have returned.
+// If ctx is done before all the calls are started, Map returns ctx.Err(),
+// and if a reservation is rejected by [Group.SetMaxPendingWeight], it returns
+// [ErrMaxPendingWeight], or [ErrNotAdmitted] if it's rejected by
+// [Group.SetAdmissionGate].
+// The results are nil if an error is returned.
+func Map[T, R any](
+	ctx context.Context,
+	g *Group,
+	items []T,
+	fn func(context.Context, T) (R, error),
+) ([]R, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var errOnce sync.Once
+	var firstErr error
+	setErr := func(err error) {
+		errOnce.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	results := make([]R, len(items))
+	wg := sync.WaitGroup{}
+	for i, item := range items {
+		if err := g.reserveCtx(ctx, 1); err != nil {
+			setErr(err)
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer g.Free()
+
+			r, err := fn(ctx, item)
+			if err != nil {
+				setErr(err)
+				return
+			}
+			results[i] = r
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return results, nil
+}
@@ -0,0 +1,79 @@
+package sema_test
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"slices"
+	"sync/atomic"
+	"testing"
+
+	"github.com/asmsh/sema"
+)
+
+func TestMap(t *testing.T) {
+	t.Parallel()
+
+	const size = 3
+
+	sg := sema.NewGroup(size)
+	items := make([]int, 50)
+	for i := range items {
+		items[i] = i
+	}
+
+	var running, maxRunning atomic.Int32
+	results, err := sema.Map(context.Background(), sg, items, func(ctx context.Context, item int) (int, error) {
+		cur := running.Add(1)
+		defer running.Add(-1)
+		for {
+			old := maxRunning.Load()
+			if cur <= old || maxRunning.CompareAndSwap(old, cur) {
+				break
+			}
+		}
+		runtime.Gosched()
+
+		return item * 2, nil
+	})
+	if err != nil {
+		t.Fatalf("Map should succeed, got: %v", err)
+	}
+
+	want := make([]int, len(items))
+	for i := range want {
+		want[i] = i * 2
+	}
+	if !slices.Equal(results, want) {
+		t.Fatalf("Map should preserve the order, got: %v", results)
+	}
+	if m := maxRunning.Load(); m > size {
+		t.Fatalf("Map should run at most %d calls at once, got %d", size, m)
+	}
+	if active := sg.ActiveCount(); active != 0 {
+		t.Fatalf("Group active count should be 0, got %d", active)
+	}
+}
+
+func TestMapError(t *testing.T) {
+	t.Parallel()
+
+	sg := sema.NewGroup(2)
+	errFirst := errors.New("first")
+
+	var started atomic.Int32
+	results, err := sema.Map(context.Background(), sg, make([]int, 100), func(ctx context.Context, item int) (int, error) {
+		if started.Add(1) == 2 {
+			return 0, errFirst
+		}
+		<-ctx.Done()
+		return 0, ctx.Err()
+	})
+	if err != errFirst || results != nil {
+		t.Fatalf("Map should return the first error, got: %v, %v", results, err)
+	}
+	if s := started.Load(); s >= 100 {
+		t.Fatalf("Map should stop starting calls after an error, started %d", s)
+	}
+	sg.Wait()
+}
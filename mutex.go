@@ -0,0 +1,150 @@
+// Copyright 2025 Ahmad Sameh(asmsh)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sema
+
+import (
+	"sync/atomic"
+)
+
+// Mutex is a mutual exclusion lock, with the same semantics as a [Group]
+// with a size of 1, but with a leaner path for uncontended Lock and Unlock
+// calls.
+//
+// The zero value is an unlocked [Mutex].
+//
+// Unlike [sync.Mutex], it supports waiting for it to be unlocked, via
+// [Mutex.Wait] and [Mutex.WaitChan], and it panics on unlocking an unlocked
+// [Mutex] with the same value that a [Group] panics with on a negative counter.
+type Mutex struct {
+	// high 32 bits are the number of blocked Lock calls, low 32 bits are 1
+	// if it's locked, like the counter of a [Group] with a size of 1.
+	// a counter of 0 means unlocked with no blocked calls, and 1 means
+	// locked with no blocked calls.
+	counter atomic.Uint64
+
+	// blockChan is created lazily by the first Lock call that blocks.
+	// it's an unbuffered channel that's never closed.
+	blockChan atomic.Value // chan struct{}
+
+	// waitChan is created lazily in Wait, exactly like the [Group]'s.
+	waitChan atomic.Value // chan struct{}
+}
+
+// Lock locks the [Mutex], blocking until it's unlocked if it's already locked.
+func (m *Mutex) Lock() {
+	// fast path: unlocked with no blocked calls.
+	if m.counter.CompareAndSwap(0, 1) {
+		return
+	}
+
+	m.lockSlow()
+}
+
+func (m *Mutex) lockSlow() {
+	// the blockChan is set before the call is counted as blocked, so it's
+	// set for the Unlock call that wakes it up.
+	blockChan := m.initBlockChan()
+
+	for {
+		counter := m.counter.Load()
+		if counter == 0 {
+			if m.counter.CompareAndSwap(0, 1) {
+				return
+			}
+			continue
+		}
+
+		if m.counter.CompareAndSwap(counter, counter+1<<32) {
+			break
+		}
+	}
+
+	// the Unlock call that wakes this call up hands the lock off to it,
+	// so it's still locked, with this call no longer counted as blocked.
+	<-blockChan
+}
+
+func (m *Mutex) initBlockChan() chan struct{} {
+	if blockChan, ok := m.blockChan.Load().(chan struct{}); ok {
+		return blockChan
+	}
+
+	m.blockChan.CompareAndSwap(nil, make(chan struct{}))
+	return m.blockChan.Load().(chan struct{})
+}
+
+// TryLock tries to lock the [Mutex] without blocking, and returns true if it
+// was successful.
+// It fails if the [Mutex] is locked, or if there are blocked [Mutex.Lock] calls.
+func (m *Mutex) TryLock() bool {
+	return m.counter.CompareAndSwap(0, 1)
+}
+
+// Unlock unlocks the [Mutex], waking up a single blocked [Mutex.Lock] call,
+// in random order, if there's any blocked.
+//
+// If there are no blocked [Mutex.Lock] calls, it will wake up any calls
+// blocked on [Mutex.Wait], and closes the [Mutex.WaitChan].
+//
+// It panics if the [Mutex] isn't locked, before updating anything.
+func (m *Mutex) Unlock() {
+	// fast path: locked with no blocked calls.
+	if m.counter.CompareAndSwap(1, 0) {
+		notifyWait(&m.waitChan, 0, 0)
+		return
+	}
+
+	m.unlockSlow()
+}
+
+func (m *Mutex) unlockSlow() {
+	for {
+		counter := m.counter.Load()
+
+		// handle any misuse before updating the counter, so the blocked
+		// calls aren't woken up by the invalid call.
+		pending, active := counterParts(counter)
+		if active == 0 {
+			panic("sema.Group: negative group counter")
+		}
+
+		if pending == 0 {
+			if m.counter.CompareAndSwap(counter, 0) {
+				notifyWait(&m.waitChan, 0, 0)
+				return
+			}
+			continue
+		}
+
+		// hand the lock off to a blocked call, which is committed to wait
+		// for it, so the send doesn't block for long.
+		if m.counter.CompareAndSwap(counter, counter-1<<32) {
+			m.blockChan.Load().(chan struct{}) <- struct{}{}
+			return
+		}
+	}
+}
+
+// Wait blocks until the [Mutex] is unlocked, with no blocked [Mutex.Lock]
+// calls, exactly like [Group.Wait].
+func (m *Mutex) Wait() {
+	<-initWaitChan(&m.waitChan, &m.counter)
+}
+
+// WaitChan returns a channel that will be closed once the [Mutex] is unlocked,
+// with no blocked [Mutex.Lock] calls, exactly like [Group.WaitChan].
+func (m *Mutex) WaitChan() <-chan struct{} {
+	return initWaitChan(&m.waitChan, &m.counter)
+}
@@ -0,0 +1,95 @@
+package sema_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/asmsh/sema"
+)
+
+func TestMutex(t *testing.T) {
+	t.Parallel()
+
+	var m sema.Mutex
+	n := 0
+
+	wg := sync.WaitGroup{}
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 1000 {
+				m.Lock()
+				n++
+				m.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	m.Wait()
+
+	if n != 8*1000 {
+		t.Errorf("the counter should be %d, got %d", 8*1000, n)
+	}
+}
+
+func TestMutexTryLock(t *testing.T) {
+	t.Parallel()
+
+	var m sema.Mutex
+	if !m.TryLock() {
+		t.Fatalf("TryLock should succeed on an unlocked Mutex")
+	}
+	if m.TryLock() {
+		t.Fatalf("TryLock should fail on a locked Mutex")
+	}
+
+	waitChan := m.WaitChan()
+	select {
+	case <-waitChan:
+		t.Fatalf("WaitChan shouldn't be closed while locked")
+	default:
+	}
+
+	m.Unlock()
+	<-waitChan
+}
+
+func TestMutexMisuse(t *testing.T) {
+	t.Parallel()
+
+	defer func() {
+		err := recover()
+		if err != "sema.Group: negative group counter" {
+			t.Fatalf("Unexpected panic: %#v", err)
+		}
+	}()
+	var m sema.Mutex
+	m.Lock()
+	m.Unlock()
+	m.Unlock()
+	t.Fatal("Should panic")
+}
+
+func TestMutexMisuseUnlocked(t *testing.T) {
+	t.Parallel()
+
+	var m sema.Mutex
+	func() {
+		defer func() {
+			err := recover()
+			if err != "sema.Group: negative group counter" {
+				t.Fatalf("Unexpected panic: %#v", err)
+			}
+		}()
+		m.Unlock()
+		t.Fatal("Should panic")
+	}()
+
+	// the invalid call shouldn't have updated anything.
+	if !m.TryLock() {
+		t.Fatalf("TryLock should succeed after the invalid Unlock")
+	}
+	m.Unlock()
+	<-m.WaitChan()
+}
@@ -0,0 +1,199 @@
+// Copyright 2025 Ahmad Sameh(asmsh)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sema
+
+import (
+	"cmp"
+	"slices"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// OpKind is the kind of a counter transition recorded in an [OpRecord].
+type OpKind int
+
+const (
+	// OpReserve is a reserve call that's granted without blocking.
+	OpReserve OpKind = iota
+
+	// OpQueue is a reserve call that's blocked, adding its n to the
+	// pending count.
+	OpQueue
+
+	// OpGrant is a blocked reserve call that's granted, moving its n from
+	// the pending count to the active count.
+	OpGrant
+
+	// OpAbort is a blocked reserve call that's aborted, removing its n
+	// from the pending count.
+	OpAbort
+
+	// OpFree is a free call.
+	OpFree
+)
+
+// String returns the name of the [OpKind].
+func (k OpKind) String() string {
+	switch k {
+	case OpReserve:
+		return "Reserve"
+	case OpQueue:
+		return "Queue"
+	case OpGrant:
+		return "Grant"
+	case OpAbort:
+		return "Abort"
+	case OpFree:
+		return "Free"
+	default:
+		return "OpKind(" + strconv.Itoa(int(k)) + ")"
+	}
+}
+
+// OpRecord is a counter transition of a [Group], as reported by
+// [Group.RecentOps].
+type OpRecord struct {
+	Kind OpKind
+
+	// Delta is the n of the call that made the transition.
+	Delta int
+
+	// Active and Pending are the counts right after the transition.
+	Active  int
+	Pending int
+
+	Time time.Time
+
+	// seq orders the records, as they're written concurrently.
+	seq uint64
+}
+
+// opLog is a ring buffer of the last counter transitions, where each record
+// claims its seq, and its slot, from an atomic index, and each slot is
+// replaced atomically, so it's written without locks.
+type opLog struct {
+	next  atomic.Uint64
+	slots []atomic.Pointer[OpRecord]
+}
+
+// EnableOpLog enables recording the last k counter transitions of the
+// [Group], which are reported by [Group.RecentOps], replacing any
+// previously recorded ones.
+//
+// It's meant for post-mortem debugging of a [Group] that hangs, as the
+// recorded transitions show the sequence of calls that led there.
+// It's expensive, as it samples the time and allocates on every transition.
+// A k less than or equal to 0 disables it, which is the default.
+func (g *Group) EnableOpLog(k int) {
+	e := g.initExt()
+	if k <= 0 {
+		e.opLog.Store(nil)
+		return
+	}
+	e.opLog.Store(&opLog{slots: make([]atomic.Pointer[OpRecord], k)})
+}
+
+// RecentOps returns the last recorded counter transitions, oldest first,
+// up to the k set via [Group.EnableOpLog].
+//
+// The transitions are recorded right after they're made, without locks, so
+// the concurrent ones might be recorded in a slightly different order than
+// they were made in, and the ones made concurrently with the call might be
+// missing.
+// It returns nil if [Group.EnableOpLog] wasn't called.
+func (g *Group) RecentOps() []OpRecord {
+	e := g.ext.Load()
+	if e == nil {
+		return nil
+	}
+	log := e.opLog.Load()
+	if log == nil {
+		return nil
+	}
+
+	var ops []OpRecord
+	for i := range log.slots {
+		if r := log.slots[i].Load(); r != nil {
+			ops = append(ops, *r)
+		}
+	}
+
+	// drop the torn records, which are the ones older than the last k, as
+	// their slots were claimed by newer records that aren't stored yet.
+	if next, k := log.next.Load(), uint64(len(log.slots)); next > k {
+		ops = slices.DeleteFunc(ops, func(r OpRecord) bool {
+			return r.seq < next-k
+		})
+	}
+
+	slices.SortFunc(ops, func(a, b OpRecord) int {
+		return cmp.Compare(a.seq, b.seq)
+	})
+	return ops
+}
+
+// logOp records a counter transition, if the op log is enabled.
+func (g *Group) logOp(kind OpKind, delta int, newCounter uint64) {
+	e := g.ext.Load()
+	if e == nil {
+		return
+	}
+	log := e.opLog.Load()
+	if log == nil {
+		return
+	}
+
+	pending, active := counterParts(newCounter)
+	seq := log.next.Add(1) - 1
+	r := &OpRecord{
+		Kind:    kind,
+		Delta:   delta,
+		Active:  int(active),
+		Pending: int(pending),
+		Time:    g.now(),
+		seq:     seq,
+	}
+
+	// a writer that stalled between claiming its seq and storing its record
+	// must not replace the newer record of a later lap in its slot.
+	slot := &log.slots[seq%uint64(len(log.slots))]
+	for {
+		old := slot.Load()
+		if old != nil && old.seq > seq {
+			return
+		}
+		if slot.CompareAndSwap(old, r) {
+			return
+		}
+	}
+}
+
+// logCounterUpdate records a counter transition made via counterUpdate,
+// deriving its kind from its deltas.
+func (g *Group) logCounterUpdate(pendingDelta, activeDelta int, newCounter uint64) {
+	switch {
+	case pendingDelta > 0:
+		g.logOp(OpQueue, pendingDelta, newCounter)
+	case pendingDelta < 0 && activeDelta > 0:
+		g.logOp(OpGrant, activeDelta, newCounter)
+	case pendingDelta < 0:
+		g.logOp(OpAbort, -pendingDelta, newCounter)
+	case activeDelta > 0:
+		g.logOp(OpReserve, activeDelta, newCounter)
+	case activeDelta < 0:
+		g.logOp(OpFree, -activeDelta, newCounter)
+	}
+}
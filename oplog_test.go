@@ -0,0 +1,98 @@
+package sema_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/asmsh/sema"
+)
+
+func TestGroupRecentOps(t *testing.T) {
+	t.Parallel()
+
+	sg := sema.NewGroup(2)
+	if ops := sg.RecentOps(); ops != nil {
+		t.Fatalf("RecentOps should be nil before EnableOpLog, got %v", ops)
+	}
+
+	sg.EnableOpLog(4)
+
+	// the Reserve below is recorded, but then replaced by the later ops.
+	sg.Reserve()
+	sg.ReserveN(nil, 1)
+
+	reserved := make(chan bool)
+	go func() {
+		reserved <- sg.ReserveN(nil, 2)
+	}()
+	for sg.PendingCount() != 2 {
+		time.Sleep(time.Millisecond)
+	}
+	sg.FreeN(2)
+	<-reserved
+
+	want := []sema.OpRecord{
+		{Kind: sema.OpReserve, Delta: 1, Active: 2, Pending: 0},
+		{Kind: sema.OpQueue, Delta: 2, Active: 2, Pending: 2},
+		{Kind: sema.OpFree, Delta: 2, Active: 0, Pending: 2},
+		{Kind: sema.OpGrant, Delta: 2, Active: 2, Pending: 0},
+	}
+	ops := sg.RecentOps()
+	if len(ops) != len(want) {
+		t.Fatalf("RecentOps should return %d ops, got %v", len(want), ops)
+	}
+	for i, op := range ops {
+		if op.Kind != want[i].Kind || op.Delta != want[i].Delta ||
+			op.Active != want[i].Active || op.Pending != want[i].Pending {
+			t.Fatalf("op %d should be %+v, got %+v", i, want[i], op)
+		}
+		if op.Time.IsZero() {
+			t.Fatalf("op %d should have its time recorded", i)
+		}
+	}
+
+	if s := sema.OpGrant.String(); s != "Grant" {
+		t.Fatalf("OpGrant should be named Grant, got %q", s)
+	}
+
+	sg.FreeN(2)
+	sg.Wait()
+}
+
+func TestGroupRecentOpsConcurrent(t *testing.T) {
+	t.Parallel()
+
+	// a Group with no limit, so every call is recorded exactly once.
+	sg := sema.NewGroup(0)
+	sg.EnableOpLog(64)
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 500 {
+				sg.Reserve()
+				sg.Free()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// once the writers are done, the log holds the last 64 records, in
+	// their seq order, with no gaps.
+	ops := sg.RecentOps()
+	if len(ops) != 64 {
+		t.Fatalf("RecentOps should return 64 ops, got %d", len(ops))
+	}
+	for i := 1; i < len(ops); i++ {
+		prev, seq := sema.OpRecordSeq(ops[i-1]), sema.OpRecordSeq(ops[i])
+		if seq != prev+1 {
+			t.Fatalf("op %d should have seq %d, got %d", i, prev+1, seq)
+		}
+	}
+	if last := sema.OpRecordSeq(ops[len(ops)-1]); last != 8*500*2-1 {
+		t.Fatalf("the last op should have seq %d, got %d", 8*500*2-1, last)
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2025 Ahmad Sameh(asmsh)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sema
+
+import (
+	"time"
+)
+
+// SetMinInterval sets the minimum interval between consecutive successful
+// reserve calls, which paces the admission into the [Group] on top of its
+// concurrency limit.
+//
+// Once set, a reserve call that gets its n reserved, either right away or
+// after blocking, also blocks until the interval has elapsed since the
+// previous paced grant, and the calls are granted in the order they got
+// their n reserved.
+// The reserved n is held while waiting for the interval, so it counts in the
+// [Group.ActiveCount], and isn't available to other calls, even though the
+// caller doesn't use it yet.
+// So with a short interval relative to the work, most of the [Group.Size]
+// can be held by calls that are only waiting for their turn.
+// If the reserve call is aborted while waiting for the interval, it frees
+// its reserved n, and returns like any aborted call.
+// The interval that was claimed by an aborted call isn't given back, so
+// it delays the next calls as if it was granted.
+//
+// [Group.TryReserveN] calls don't wait for the interval, and fail if it
+// hasn't elapsed yet.
+//
+// A d less than or equal to 0 disables the pacing, which is the default.
+func (g *Group) SetMinInterval(d time.Duration) {
+	// the epoch is set before the interval, so it's set once pacing is.
+	e := g.initExt()
+	epoch := g.now()
+	e.paceEpoch.CompareAndSwap(nil, &epoch)
+	e.minInterval.Store(int64(max(d, 0)))
+}
+
+// pacing reports whether the pacing is enabled, via [Group.SetMinInterval].
+// It's called on a nil e too, where it's disabled.
+func (e *groupExt) pacing() bool {
+	return e != nil && e.minInterval.Load() != 0
+}
+
+// paceNow returns the current time as stored in nextGrant, which is the
+// duration since the pace epoch, to use the monotonic clock, if any.
+func (g *Group) paceNow(e *groupExt) int64 {
+	return int64(g.now().Sub(*e.paceEpoch.Load()))
+}
+
+// pace claims the next paced grant time, and blocks until it's reached.
+// It returns false if doneChan, or the timer in opts, was done before that.
+// The callers check that the pacing is enabled before calling it, so it's
+// free while it's disabled.
+func (g *Group) pace(doneChan <-chan struct{}, opts *reserveOpts) bool {
+	e := g.ext.Load()
+	if !e.pacing() {
+		return true
+	}
+
+	interval := e.minInterval.Load()
+	now := g.paceNow(e)
+	var at int64
+	for {
+		next := e.nextGrant.Load()
+		at = max(now, next)
+		if e.nextGrant.CompareAndSwap(next, at+interval) {
+			break
+		}
+	}
+
+	if at == now {
+		return true
+	}
+
+	timerChan, stop := g.newTimer(time.Duration(at - now))
+	defer stop()
+
+	select {
+	case <-timerChan:
+		return true
+	case <-doneChan:
+		return false
+	case <-opts.timer():
+		return false
+	}
+}
+
+// tryPace claims the next paced grant time only if it's already reached.
+func (g *Group) tryPace() bool {
+	e := g.ext.Load()
+	if !e.pacing() {
+		return true
+	}
+
+	interval := e.minInterval.Load()
+	now := g.paceNow(e)
+	for {
+		next := e.nextGrant.Load()
+		if next > now {
+			return false
+		}
+		if e.nextGrant.CompareAndSwap(next, now+interval) {
+			return true
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package sema_test
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/asmsh/sema"
+)
+
+func TestGroupSetMinInterval(t *testing.T) {
+	t.Parallel()
+
+	const interval = 20 * time.Millisecond
+	const callers = 5
+
+	sg := sema.NewGroup(callers)
+	sg.SetMinInterval(interval)
+
+	start := time.Now()
+	granted := make([]time.Duration, callers)
+	wg := sync.WaitGroup{}
+	for i := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sg.Reserve()
+			granted[i] = time.Since(start)
+		}()
+	}
+	wg.Wait()
+
+	// every grant waits for its own claimed time, so the grants are spaced
+	// by the interval, allowing for some scheduling delay of the previous
+	// grant.
+	slices.Sort(granted)
+	minSpan := (callers-1)*interval - interval/2
+	if span := granted[callers-1] - granted[0]; span < minSpan {
+		t.Errorf("grants should span at least %v, got %v", minSpan, span)
+	}
+	for i := 1; i < callers; i++ {
+		if gap := granted[i] - granted[i-1]; gap < interval/2 {
+			t.Errorf("grants should be spaced by %v, got %v", interval, gap)
+		}
+	}
+
+	// a TryReserveN call right after a grant fails, and gives its n back.
+	if sg.TryReserveN(1) {
+		t.Fatalf("TryReserveN should fail before the interval elapses")
+	}
+	if active := sg.ActiveCount(); active != callers {
+		t.Fatalf("Group active count should be %d, got %d", callers, active)
+	}
+
+	// an aborted wait for the interval frees the reserved n.
+	sg.FreeN(callers)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if sg.ReserveN(ctx.Done(), 1) {
+		t.Fatalf("ReserveN should be aborted while waiting for the interval")
+	}
+	if active := sg.ActiveCount(); active != 0 {
+		t.Fatalf("Group active count should be 0, got %d", active)
+	}
+}
+
+func TestGroupSetMinIntervalShortfall(t *testing.T) {
+	t.Parallel()
+
+	sg := sema.NewGroup(2)
+	sg.SetMinInterval(time.Hour)
+	sg.Reserve()
+
+	// there's room, but the interval hasn't elapsed, so nothing is short.
+	ok, shortfall := sg.TryReserveNOrShortfall(1)
+	if ok || shortfall != 0 {
+		t.Fatalf("TryReserveNOrShortfall should fail with 0 shortfall, got %v and %d", ok, shortfall)
+	}
+	if active := sg.ActiveCount(); active != 1 {
+		t.Fatalf("Group active count should be 1, got %d", active)
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2025 Ahmad Sameh(asmsh)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sema
+
+import (
+	"math"
+)
+
+// Pipeline couples the progress of consecutive stages, so a stage can't
+// advance more than a bounded lookahead past the stage before it, which
+// is the bounded-lookahead coupling of a multi-stage pipeline.
+//
+// Each pair of consecutive stages is coupled by a gate [Group], whose active
+// count is the lead of the later stage over the earlier one, offset by a
+// base, so the later stage blocks on the size of the gate, while the earlier
+// stage only frees from it, and is never blocked by it.
+type Pipeline struct {
+	gates  []Group
+	stages []Stage
+}
+
+// Stage is a stage of a [Pipeline], as returned by [Pipeline.Stage].
+type Stage struct {
+	p *Pipeline
+	i int
+}
+
+// pipelineBase is the offset of the gate counts, which bounds how far
+// a stage can advance past the stage after it.
+const pipelineBase = math.MaxInt32 / 2
+
+// NewPipeline creates a new [Pipeline] of the provided number of stages,
+// where each stage can advance at most ahead steps past the stage before it.
+//
+// It panics if stages is less than 2, or if ahead is less than 0 or greater
+// than [math.MaxInt32]/2.
+func NewPipeline(stages, ahead int) *Pipeline {
+	if stages < 2 {
+		panic("sema.Pipeline: invalid stages count")
+	}
+	if ahead < 0 || ahead > pipelineBase {
+		panic("sema.Pipeline: invalid ahead value")
+	}
+
+	p := &Pipeline{
+		gates:  make([]Group, stages-1),
+		stages: make([]Stage, stages),
+	}
+	for i := range p.gates {
+		p.gates[i].setSize(pipelineBase + ahead)
+		p.gates[i].ReserveN(nil, pipelineBase)
+	}
+	for i := range p.stages {
+		p.stages[i] = Stage{p: p, i: i}
+	}
+	return p
+}
+
+// Stages returns the number of stages of the [Pipeline].
+func (p *Pipeline) Stages() int {
+	return len(p.stages)
+}
+
+// Stage returns the i-th stage of the [Pipeline], where the 0-th stage is
+// the first one, which is never blocked.
+//
+// It panics if i is less than 0, or greater than or equal to
+// [Pipeline.Stages].
+func (p *Pipeline) Stage(i int) *Stage {
+	if i < 0 || i >= len(p.stages) {
+		panic("sema.Pipeline: invalid stage index")
+	}
+	return &p.stages[i]
+}
+
+// Advance advances the [Stage] by one step, blocking until it's less than
+// ahead steps past the stage before it, if any.
+// Advancing the [Stage] lets the stage after it, if any, advance one more
+// step.
+//
+// A [Stage] can advance about [math.MaxInt32]/2 steps past the stage after
+// it, beyond which it panics.
+func (s *Stage) Advance() {
+	s.AdvanceUntil(nil)
+}
+
+// AdvanceUntil advances the [Stage] exactly like [Stage.Advance], but returns
+// false without advancing it if the provided doneChan becomes receive-ready
+// while it's blocked.
+func (s *Stage) AdvanceUntil(doneChan <-chan struct{}) bool {
+	gates := s.p.gates
+
+	// the stage's lead over the stage before it is bounded by its gate.
+	if s.i > 0 && !gates[s.i-1].ReserveN(doneChan, 1) {
+		return false
+	}
+
+	// and it lets the stage after it advance one more step.
+	if s.i < len(gates) {
+		gates[s.i].Free()
+	}
+	return true
+}
+
+// Lead returns how many steps the [Stage] is past the stage before it,
+// which is negative if it's behind it, and at most the ahead value.
+// It's always 0 for the first stage.
+func (s *Stage) Lead() int {
+	if s.i == 0 {
+		return 0
+	}
+	return s.p.gates[s.i-1].ActiveCount() - pipelineBase
+}
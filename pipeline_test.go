@@ -0,0 +1,129 @@
+package sema_test
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/asmsh/sema"
+)
+
+func TestPipeline(t *testing.T) {
+	t.Parallel()
+
+	p := sema.NewPipeline(2, 2)
+	up, down := p.Stage(0), p.Stage(1)
+
+	// the downstream can advance ahead of the upstream, up to the limit.
+	down.Advance()
+	down.Advance()
+	if lead := down.Lead(); lead != 2 {
+		t.Fatalf("downstream lead should be 2, got %d", lead)
+	}
+
+	doneChan := make(chan struct{})
+	close(doneChan)
+	if down.AdvanceUntil(doneChan) {
+		t.Fatalf("AdvanceUntil should fail at the lookahead limit")
+	}
+
+	// the upstream is never blocked, and lets the downstream advance.
+	for range 5 {
+		up.Advance()
+	}
+	if lead := down.Lead(); lead != -3 {
+		t.Fatalf("downstream lead should be -3, got %d", lead)
+	}
+	for range 5 {
+		down.Advance()
+	}
+	if down.AdvanceUntil(doneChan) {
+		t.Fatalf("AdvanceUntil should fail at the lookahead limit")
+	}
+}
+
+func TestPipelineConcurrent(t *testing.T) {
+	t.Parallel()
+
+	const (
+		stages  = 3
+		ahead   = 4
+		workers = 4
+		steps   = 1000
+	)
+
+	p := sema.NewPipeline(stages, ahead)
+
+	// advanced counts the steps of each stage, where a stage's count is
+	// incremented before it's advanced, and the next stage's count is
+	// incremented after it's advanced, so the checked lead never falls
+	// short of the real one.
+	var advanced [stages]atomic.Int64
+	var violations atomic.Int64
+
+	var wg sync.WaitGroup
+	for i := range stages {
+		for range workers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for range steps {
+					if i < stages-1 {
+						advanced[i].Add(1)
+						p.Stage(i).Advance()
+						continue
+					}
+					p.Stage(i).Advance()
+					n := advanced[i].Add(1)
+					if n > advanced[i-1].Load()+ahead {
+						violations.Add(1)
+					}
+				}
+			}()
+		}
+	}
+
+	// the middle stage checks its own lead, as it's both blocked and freed.
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for range steps {
+			if lead := p.Stage(1).Lead(); lead > ahead {
+				violations.Add(1)
+			}
+			time.Sleep(time.Microsecond)
+		}
+	}()
+
+	wg.Wait()
+	if v := violations.Load(); v != 0 {
+		t.Fatalf("lookahead invariant was violated %d times", v)
+	}
+	for i := 1; i < stages; i++ {
+		if lead := p.Stage(i).Lead(); lead != 0 {
+			t.Fatalf("stage %d lead should be 0 once all stages are done, got %d", i, lead)
+		}
+	}
+}
+
+func TestNewPipelinePanic(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name          string
+		stages, ahead int
+	}{
+		{"single stage", 1, 1},
+		{"negative ahead", 2, -1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("NewPipeline should panic")
+				}
+			}()
+			sema.NewPipeline(tc.stages, tc.ahead)
+		})
+	}
+}
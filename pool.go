@@ -0,0 +1,121 @@
+// Copyright 2025 Ahmad Sameh(asmsh)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sema
+
+import (
+	"context"
+	"sync"
+)
+
+// Pool is a fixed pool of resources, where each resource is used by at most
+// one holder at a time.
+// It's built on a [Group] with a size equal to the number of resources, which
+// provides its blocking behavior.
+type Pool[T any] struct {
+	g Group
+
+	mu        sync.Mutex
+	resources []T // the resources that are not held.
+	policy    ReusePolicy
+}
+
+// ReusePolicy is the order that a [Pool] hands out its released resources,
+// which is set via [Pool.SetReusePolicy].
+type ReusePolicy int
+
+const (
+	// ReuseLIFO hands out the most recently released resource first, so
+	// under a low load, a small hot subset of the resources is reused,
+	// which keeps them warm, like their caches or connections.
+	// It's the default.
+	ReuseLIFO ReusePolicy = iota
+
+	// ReuseFIFO hands out the least recently released resource first, so
+	// the use is spread evenly across all the resources.
+	ReuseFIFO
+)
+
+// NewPool creates a new [Pool] of the provided resources.
+//
+// It panics if resources is empty.
+func NewPool[T any](resources []T) *Pool[T] {
+	if len(resources) == 0 {
+		panic("sema.Pool: no pool resources")
+	}
+
+	p := &Pool[T]{resources: append([]T(nil), resources...)}
+	p.g.setSize(len(resources))
+	return p
+}
+
+// Acquire waits for a resource to be available, exactly like [Group.Reserve],
+// and returns it with a release func that returns it back to the [Pool].
+// The release func must be called once the resource is no longer used, and
+// calling it more than once has no effect.
+//
+// It returns ctx.Err() if ctx is done before a resource is available.
+func (p *Pool[T]) Acquire(ctx context.Context) (res T, release func(), err error) {
+	if err := p.g.reserveCtx(ctx, 1); err != nil {
+		return res, nil, err
+	}
+
+	p.mu.Lock()
+	if p.policy == ReuseFIFO {
+		res = p.resources[0]
+		p.resources = p.resources[1:]
+	} else {
+		last := len(p.resources) - 1
+		res = p.resources[last]
+		p.resources = p.resources[:last]
+	}
+	p.mu.Unlock()
+
+	var once sync.Once
+	release = func() {
+		once.Do(func() {
+			p.mu.Lock()
+			p.resources = append(p.resources, res)
+			p.mu.Unlock()
+
+			p.g.Free()
+		})
+	}
+
+	return res, release, nil
+}
+
+// SetReusePolicy sets the order that the released resources are handed out
+// by the next [Pool.Acquire] calls, which is [ReuseLIFO] by default.
+func (p *Pool[T]) SetReusePolicy(policy ReusePolicy) {
+	p.mu.Lock()
+	p.policy = policy
+	p.mu.Unlock()
+}
+
+// Size returns the number of resources in the [Pool].
+func (p *Pool[T]) Size() int {
+	return p.g.Size()
+}
+
+// ActiveCount returns the number of resources that are currently held.
+func (p *Pool[T]) ActiveCount() int {
+	return p.g.ActiveCount()
+}
+
+// Wait blocks until all the held resources are released, exactly like
+// [Group.Wait].
+func (p *Pool[T]) Wait() {
+	p.g.Wait()
+}
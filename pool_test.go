@@ -0,0 +1,116 @@
+package sema_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/asmsh/sema"
+)
+
+func TestPool(t *testing.T) {
+	t.Parallel()
+
+	t.Run("the resources are held by one holder at a time", func(t *testing.T) {
+		resources := []int{1, 2, 3, 4}
+		p := sema.NewPool(resources)
+
+		var mu sync.Mutex
+		held := map[int]bool{}
+		maxHeld := 0
+
+		wg := sync.WaitGroup{}
+		for range 64 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				res, release, err := p.Acquire(context.Background())
+				if err != nil {
+					t.Errorf("Acquire should succeed, got %v", err)
+					return
+				}
+
+				mu.Lock()
+				if held[res] {
+					t.Errorf("resource %d is held twice", res)
+				}
+				held[res] = true
+				maxHeld = max(maxHeld, len(held))
+				mu.Unlock()
+
+				time.Sleep(time.Millisecond)
+
+				mu.Lock()
+				delete(held, res)
+				mu.Unlock()
+
+				release()
+				release()
+			}()
+		}
+		wg.Wait()
+		p.Wait()
+
+		if maxHeld != len(resources) {
+			t.Errorf("the max held resources should be %d, got %d", len(resources), maxHeld)
+		}
+		if active := p.ActiveCount(); active != 0 {
+			t.Errorf("Pool active count should be 0, got %d", active)
+		}
+	})
+
+	t.Run("the ctx error is returned if no resource is available", func(t *testing.T) {
+		p := sema.NewPool([]string{"conn"})
+
+		_, release, err := p.Acquire(context.Background())
+		if err != nil {
+			t.Fatalf("Acquire should succeed, got %v", err)
+		}
+		defer release()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		if _, _, err := p.Acquire(ctx); err != context.DeadlineExceeded {
+			t.Errorf("Acquire should fail with %v, got %v", context.DeadlineExceeded, err)
+		}
+	})
+}
+
+func TestPoolSetReusePolicy(t *testing.T) {
+	t.Parallel()
+
+	// acquireSequentially acquires and releases a resource n times, one
+	// at a time, and returns the distinct resources that were handed out.
+	acquireSequentially := func(p *sema.Pool[int], n int) map[int]int {
+		used := map[int]int{}
+		for range n {
+			res, release, err := p.Acquire(context.Background())
+			if err != nil {
+				t.Fatalf("Acquire should succeed, got %v", err)
+			}
+			used[res]++
+			release()
+		}
+		return used
+	}
+
+	resources := []int{1, 2, 3, 4}
+
+	// under a steady low load, LIFO keeps reusing the same resource.
+	lifo := sema.NewPool(resources)
+	if used := acquireSequentially(lifo, 20); len(used) != 1 {
+		t.Fatalf("LIFO should reuse a single hot resource, got %v", used)
+	}
+
+	// while FIFO spreads the use evenly across all the resources.
+	fifo := sema.NewPool(resources)
+	fifo.SetReusePolicy(sema.ReuseFIFO)
+	used := acquireSequentially(fifo, 20)
+	for _, res := range resources {
+		if used[res] != 5 {
+			t.Fatalf("FIFO should use each resource 5 times, got %v", used)
+		}
+	}
+}
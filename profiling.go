@@ -0,0 +1,56 @@
+// Copyright 2025 Ahmad Sameh(asmsh)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sema
+
+import (
+	"context"
+	"runtime/pprof"
+)
+
+// profilingLabelKey is the pprof label key set on blocked reserve calls.
+const profilingLabelKey = "sema_group"
+
+// profilingUnnamedLabel is the pprof label value of the blocked reserve
+// calls of a [Group] without a name.
+const profilingUnnamedLabel = "(unnamed)"
+
+// EnableProfiling makes the blocking reserve calls that take a ctx, like
+// [Group.ReserveNContext], run under a pprof label with the key "sema_group"
+// and the [Group.Name] as its value, or "(unnamed)" if it has no name, so the
+// goroutines blocked on this [Group] and the CPU time spent while contended
+// are attributable to it, in the goroutine and CPU profiles.
+//
+// The label is added to the labels of the ctx, as set via [pprof.WithLabels],
+// and the goroutine's labels are set back to them once the call returns.
+// The calls that don't take a ctx, like [Group.ReserveN], aren't labeled,
+// as the labels of their goroutines can't be restored without it.
+//
+// It only affects the calls that block, and it doesn't change their behavior.
+// When it's not enabled, no labels are set, and there's no extra overhead.
+func (g *Group) EnableProfiling() {
+	g.initExt().profiling.Store(true)
+}
+
+func (g *Group) reserveNSlowProfiled(size uint32, doneChan <-chan struct{}, reserveN int, opts *reserveOpts) (reserved bool) {
+	name := g.name
+	if name == "" {
+		name = profilingUnnamedLabel
+	}
+
+	pprof.Do(opts.ctx, pprof.Labels(profilingLabelKey, name), func(context.Context) {
+		reserved = g.reserveNSlow(size, doneChan, reserveN, opts)
+	})
+	return reserved
+}
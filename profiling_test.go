@@ -0,0 +1,100 @@
+package sema_test
+
+import (
+	"bytes"
+	"context"
+	"runtime/pprof"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asmsh/sema"
+)
+
+// goroutineProfile returns the goroutine profile, with the labels of each
+// goroutine.
+func goroutineProfile(t *testing.T) string {
+	t.Helper()
+
+	buf := bytes.Buffer{}
+	if err := pprof.Lookup("goroutine").WriteTo(&buf, 1); err != nil {
+		t.Fatalf("writing the goroutine profile failed: %v", err)
+	}
+	return buf.String()
+}
+
+func TestGroupEnableProfiling(t *testing.T) {
+	t.Parallel()
+
+	sg := sema.NewNamedGroup("downloads", 1)
+	sg.EnableProfiling()
+	sg.Reserve()
+
+	reserved := make(chan struct{})
+	release := make(chan struct{})
+	go pprof.Do(context.Background(), pprof.Labels("caller", "profiled"), func(ctx context.Context) {
+		if err := sg.ReserveContext(ctx); err != nil {
+			t.Errorf("ReserveContext failed: %v", err)
+		}
+		close(reserved)
+		<-release
+	})
+	for sg.PendingCount() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// the blocked call keeps the labels of its ctx.
+	profile := goroutineProfile(t)
+	if label := `"caller":"profiled", "sema_group":"downloads"`; !strings.Contains(profile, label) {
+		t.Errorf("goroutine profile should contain the %s labels", label)
+	}
+
+	// the labeled call behaves like any other blocked call.
+	sg.Free()
+	<-reserved
+	if active := sg.ActiveCount(); active != 1 {
+		t.Errorf("Group active count should be 1, got %d", active)
+	}
+	if pending := sg.PendingCount(); pending != 0 {
+		t.Errorf("Group pending count should be 0, got %d", pending)
+	}
+
+	// the goroutine gets the labels of its ctx back once the call returns.
+	profile = goroutineProfile(t)
+	if label := `"caller":"profiled"`; !strings.Contains(profile, label) {
+		t.Errorf("goroutine profile should contain the %s label", label)
+	}
+	if label := `"sema_group":"downloads"`; strings.Contains(profile, label) {
+		t.Errorf("goroutine profile shouldn't contain the %s label", label)
+	}
+
+	close(release)
+	sg.Free()
+}
+
+func TestGroupEnableProfilingUnnamed(t *testing.T) {
+	t.Parallel()
+
+	sg := sema.NewGroup(1)
+	sg.EnableProfiling()
+	sg.Reserve()
+
+	reserved := make(chan struct{})
+	go func() {
+		if err := sg.ReserveContext(context.Background()); err != nil {
+			t.Errorf("ReserveContext failed: %v", err)
+		}
+		close(reserved)
+	}()
+	for sg.PendingCount() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if label := `"sema_group":"(unnamed)"`; !strings.Contains(goroutineProfile(t), label) {
+		t.Errorf("goroutine profile should contain the %s label", label)
+	}
+
+	sg.Free()
+	<-reserved
+	sg.Free()
+}
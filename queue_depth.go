@@ -0,0 +1,141 @@
+// Copyright 2025 Ahmad Sameh(asmsh)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sema
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// queueDepthTracker maintains a time-weighted exponential moving average
+// of the pending count.
+type queueDepthTracker struct {
+	window  float64 // in nanoseconds.
+	now     func() time.Time
+	counter *atomic.Uint64
+
+	// dirty is set when the pending count changes, so the current holder of
+	// the mutex, if any, samples it again before it's done.
+	dirty atomic.Bool
+
+	mu          sync.Mutex
+	last        time.Time // the time of the last sample.
+	lastPending uint32    // the pending count since the last sample.
+	avg         float64
+}
+
+// sample records that the pending count changed.
+//
+// The pending count is read from the counter while holding the mutex,
+// rather than passed by the caller, so concurrent samples can't store the
+// counts out of order.
+// It never blocks, as a sample that finds the mutex held leaves it to the
+// holder, which keeps sampling until no changes are left.
+func (t *queueDepthTracker) sample() {
+	t.dirty.Store(true)
+	t.drain()
+}
+
+// drain samples the pending count until no changes are left, unless the
+// mutex is held, as its holder drains them instead.
+func (t *queueDepthTracker) drain() {
+	for t.dirty.Load() && t.mu.TryLock() {
+		t.update()
+		t.mu.Unlock()
+	}
+}
+
+// update decays the average up to now, and then stores the current
+// pending count.
+// it must be called while holding the mutex.
+func (t *queueDepthTracker) update() {
+	t.dirty.Store(false)
+	pending, _ := counterParts(t.counter.Load())
+
+	t.advance(t.now())
+	t.lastPending = pending
+}
+
+// advance decays the average up to now, weighting the pending count since
+// the last sample by how long it lasted.
+// it must be called while holding the mutex.
+func (t *queueDepthTracker) advance(now time.Time) {
+	dt := float64(now.Sub(t.last))
+	if dt <= 0 {
+		return
+	}
+
+	alpha := 1 - math.Exp(-dt/t.window)
+	t.avg = math.FMA(alpha, float64(t.lastPending)-t.avg, t.avg)
+	t.last = now
+}
+
+// EnableQueueDepthTracking enables tracking the average [Group.PendingCount]
+// over time, which is reported by [Group.AvgQueueDepth].
+//
+// The average is an exponential moving average, weighted by how long each
+// [Group.PendingCount] value lasted, where values older than the provided
+// window have a diminishing effect on it.
+// It's a smoothed signal that's more stable than the [Group.PendingCount],
+// which makes it more suitable for scaling decisions.
+//
+// Calling it again resets the average, and calling it with a zero or
+// negative window disables the tracking.
+//
+// While enabled, every update to the [Group.PendingCount] samples the time
+// to update the average, without blocking on concurrent updates.
+func (g *Group) EnableQueueDepthTracking(window time.Duration) {
+	e := g.initExt()
+	if window <= 0 {
+		e.queueDepth.Store(nil)
+		return
+	}
+
+	pending, _ := counterParts(g.counter.Load())
+	e.queueDepth.Store(&queueDepthTracker{
+		window:      float64(window),
+		now:         g.now,
+		counter:     &g.counter,
+		last:        g.now(),
+		lastPending: pending,
+		avg:         float64(pending),
+	})
+}
+
+// AvgQueueDepth returns the average [Group.PendingCount], as tracked after
+// calling [Group.EnableQueueDepthTracking].
+//
+// It returns 0 if the tracking isn't enabled.
+func (g *Group) AvgQueueDepth() float64 {
+	e := g.ext.Load()
+	if e == nil {
+		return 0
+	}
+	qd := e.queueDepth.Load()
+	if qd == nil {
+		return 0
+	}
+
+	qd.mu.Lock()
+	qd.update()
+	avg := qd.avg
+	qd.mu.Unlock()
+
+	// drain the changes sampled while the mutex was held.
+	qd.drain()
+	return avg
+}
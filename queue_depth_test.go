@@ -0,0 +1,99 @@
+package sema_test
+
+import (
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/asmsh/sema"
+)
+
+func TestGroupAvgQueueDepth(t *testing.T) {
+	t.Parallel()
+
+	const window = 10 * time.Millisecond
+
+	sg := sema.NewGroup(1)
+	fc := newFakeClock(sg)
+	if avg := sg.AvgQueueDepth(); avg != 0 {
+		t.Errorf("average queue depth should be 0 when disabled, got %v", avg)
+	}
+
+	sg.EnableQueueDepthTracking(window)
+	sg.Reserve()
+
+	reserved := make(chan struct{})
+	go func() {
+		sg.Reserve()
+		close(reserved)
+	}()
+
+	// keep 1 pending for several windows.
+	for sg.PendingCount() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	fc.Advance(5 * window)
+
+	want := 1 - math.Exp(-5)
+	if avg := sg.AvgQueueDepth(); math.Abs(avg-want) > 1e-9 {
+		t.Errorf("average queue depth should be %v, got %v", want, avg)
+	}
+
+	// drop the pending to 0 for several windows.
+	sg.Free()
+	<-reserved
+	fc.Advance(5 * window)
+
+	want *= math.Exp(-5)
+	if avg := sg.AvgQueueDepth(); math.Abs(avg-want) > 1e-9 {
+		t.Errorf("average queue depth should be %v, got %v", want, avg)
+	}
+	sg.Free()
+}
+
+func TestGroupAvgQueueDepthConcurrent(t *testing.T) {
+	t.Parallel()
+
+	const window = 10 * time.Millisecond
+
+	sg := sema.NewGroup(2)
+	fc := newFakeClock(sg)
+	sg.EnableQueueDepthTracking(window)
+
+	var wg sync.WaitGroup
+	for range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 200 {
+				sg.Reserve()
+				sg.Free()
+			}
+		}()
+	}
+
+	// advance the clock while the calls run, so the pending counts they
+	// make are weighted by how long they lasted.
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+			fc.Advance(time.Millisecond)
+		}
+	}
+
+	// the group is drained, so the average should settle to 0 within
+	// several windows, unless a stale pending count was sampled last.
+	fc.Advance(10 * window)
+
+	if avg := sg.AvgQueueDepth(); avg < 0 || avg > 0.01 {
+		t.Errorf("average queue depth should settle to 0, got %v", avg)
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2025 Ahmad Sameh(asmsh)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sema
+
+// reentrantHold is a reservation made via [Group.ReserveReentrant].
+type reentrantHold struct {
+	n     int
+	depth int
+}
+
+// ReserveReentrant reserves n on behalf of the provided token, blocking if
+// needed, exactly like [Group.Reserve], unless the token already holds a
+// reservation, in which case it only increments the token's depth and
+// returns immediately, without reserving anything against the [Group.Size].
+// This avoids the self-deadlock of a nested reservation by the same owner.
+//
+// The n of a nested call is ignored, as it's covered by the outer reservation.
+// Each call must be matched by a [Group.FreeReentrant] call with the same
+// token, and only the last one frees the reserved n.
+//
+// It returns false, without reserving anything, if n is greater than the
+// [Group.Size].
+//
+// The token must be unique per logical owner, and must not be used by multiple
+// goroutines at the same time, otherwise they would share the same reservation.
+// It must be comparable, as it's used as a map key.
+//
+// It panics if n is less than or equal to 0, or greater than [math.MaxInt32].
+func (g *Group) ReserveReentrant(token any, n int) bool {
+	e := g.initExt()
+	e.reentrantMu.Lock()
+	if h := e.reentrant[token]; h != nil {
+		h.depth++
+		e.reentrantMu.Unlock()
+		return true
+	}
+	e.reentrantMu.Unlock()
+
+	if !g.reserveNWait(nil, n) {
+		return false
+	}
+
+	e.reentrantMu.Lock()
+	if e.reentrant == nil {
+		e.reentrant = make(map[any]*reentrantHold)
+	}
+	e.reentrant[token] = &reentrantHold{n: n, depth: 1}
+	e.reentrantMu.Unlock()
+
+	return true
+}
+
+// FreeReentrant decrements the depth of the reservation held by the provided
+// token, and frees its reserved n, exactly like [Group.FreeN], once the depth
+// reaches zero.
+//
+// It panics if the token doesn't hold a reservation.
+func (g *Group) FreeReentrant(token any) {
+	e := g.initExt()
+	e.reentrantMu.Lock()
+	h := e.reentrant[token]
+	if h == nil {
+		e.reentrantMu.Unlock()
+		g.panic("free of a non-reserved reentrant token")
+	}
+
+	h.depth--
+	if h.depth > 0 {
+		e.reentrantMu.Unlock()
+		return
+	}
+	delete(e.reentrant, token)
+	e.reentrantMu.Unlock()
+
+	g.FreeN(h.n)
+}
@@ -0,0 +1,49 @@
+package sema_test
+
+import (
+	"testing"
+
+	"github.com/asmsh/sema"
+)
+
+func TestGroupReserveReentrant(t *testing.T) {
+	t.Parallel()
+
+	sg := sema.NewGroup(2)
+	owner := new(int)
+
+	// a nested reservation by the same owner doesn't block.
+	if !sg.ReserveReentrant(owner, 2) {
+		t.Fatalf("ReserveReentrant should succeed")
+	}
+	if !sg.ReserveReentrant(owner, 2) {
+		t.Fatalf("nested ReserveReentrant should succeed")
+	}
+	if active := sg.ActiveCount(); active != 2 {
+		t.Errorf("Group active count should be 2, got %d", active)
+	}
+
+	// only the last free frees the reservation.
+	sg.FreeReentrant(owner)
+	if active := sg.ActiveCount(); active != 2 {
+		t.Errorf("Group active count should be 2, got %d", active)
+	}
+	sg.FreeReentrant(owner)
+	if active := sg.ActiveCount(); active != 0 {
+		t.Errorf("Group active count should be 0, got %d", active)
+	}
+
+	// a reservation that can never succeed fails right away.
+	if sg.ReserveReentrant(owner, 3) {
+		t.Fatalf("ReserveReentrant should fail")
+	}
+
+	defer func() {
+		err := recover()
+		if err != "sema.Group: free of a non-reserved reentrant token" {
+			t.Fatalf("Unexpected panic: %#v", err)
+		}
+	}()
+	sg.FreeReentrant(owner)
+	t.Fatal("Should panic")
+}
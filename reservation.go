@@ -0,0 +1,372 @@
+// Copyright 2025 Ahmad Sameh(asmsh)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sema
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Reservation is a handle to the n reserved from a [Group], as returned by
+// [Group.Acquire], which remembers its n, so it's freed without repeating it.
+type Reservation struct {
+	g        *Group
+	n        int
+	released atomic.Bool
+
+	// acquiredAt is when the [Reservation] was made, for the hold time
+	// reported by [Group.EstimatedWait].
+	acquiredAt time.Time
+
+	// stack is the stack of the Acquire call, captured only while acquire
+	// tracking is enabled via [Group.EnableAcquireTracking].
+	stack []uintptr
+
+	// releasedChan is closed on release, only if the hold duration is limited
+	// via [Group.SetMaxHoldDuration], to stop watching the hold.
+	releasedChan chan struct{}
+}
+
+// maxHold is the config set via [Group.SetMaxHoldDuration].
+type maxHold struct {
+	d         time.Duration
+	onReclaim func(n int, held time.Duration)
+}
+
+// Acquire reserves n, blocking if needed, exactly like [Group.ReserveN],
+// and returns a [Reservation] handle to free it via [Reservation.Release].
+//
+// It returns false with a nil [Reservation] if the reservation wasn't made.
+//
+// It panics if n is less than or equal to 0, or greater than [math.MaxInt32].
+func (g *Group) Acquire(doneChan <-chan struct{}, n int) (*Reservation, bool) {
+	if !g.reserveNWait(doneChan, n) {
+		return nil, false
+	}
+
+	// the hold time is recorded for every [Reservation], so it's opt-in too.
+	e := g.initExt()
+	r := &Reservation{g: g, n: n, acquiredAt: g.now()}
+	if e.acquireTracking.Load() {
+		// skip runtime.Callers and Acquire itself.
+		pcs := make([]uintptr, 32)
+		r.stack = pcs[:runtime.Callers(2, pcs)]
+		g.track(r)
+	}
+
+	if hold := e.maxHold.Load(); hold != nil {
+		r.releasedChan = make(chan struct{})
+		go r.reclaimAfter(hold)
+	}
+
+	return r, true
+}
+
+// reclaimAfter frees the n held by the [Reservation] once it's held for
+// longer than the provided hold duration, unless it's released before that.
+func (r *Reservation) reclaimAfter(hold *maxHold) {
+	timerChan, stop := r.g.newTimer(hold.d)
+	defer stop()
+
+	select {
+	case <-r.releasedChan:
+		return
+	case <-timerChan:
+	}
+
+	if held, ok := r.release(); ok && hold.onReclaim != nil {
+		hold.onReclaim(r.n, held)
+	}
+}
+
+// N returns the n held by the [Reservation].
+func (r *Reservation) N() int {
+	return r.n
+}
+
+// Release frees the n held by the [Reservation], exactly like [Group.FreeN].
+// It's safe to call multiple times, and frees the n exactly once.
+func (r *Reservation) Release() {
+	if _, ok := r.release(); ok && r.releasedChan != nil {
+		close(r.releasedChan)
+	}
+}
+
+// release frees the n held by the [Reservation], and returns how long it
+// was held, and true, only if it's not released yet.
+func (r *Reservation) release() (held time.Duration, ok bool) {
+	if !r.released.CompareAndSwap(false, true) {
+		return 0, false
+	}
+
+	if r.stack != nil {
+		r.g.untrack(r)
+	}
+	held = r.g.now().Sub(r.acquiredAt)
+	r.g.recordHold(held)
+	r.g.FreeN(r.n)
+	return held, true
+}
+
+func (g *Group) recordHold(held time.Duration) {
+	e := g.initExt()
+	e.holdTotal.Add(int64(held))
+	e.holdCount.Add(1)
+}
+
+// EstimatedWait returns an estimate of how long a reserve call of 1 that's
+// made now would block, which is 0 if it wouldn't block.
+//
+// It's based on the [Group.PendingCount], and the average time that the
+// reservations made via [Group.Acquire] were held for, assuming that each
+// of the [Group.Size] held slots is freed after that average, at random.
+// So it's only an approximation, but it lets a caller skip reserving when
+// the wait would be too long.
+//
+// It returns 0 if the [Group.Size] is 0, or if no [Reservation] was
+// released yet.
+func (g *Group) EstimatedWait() time.Duration {
+	e := g.ext.Load()
+	if e == nil {
+		return 0
+	}
+
+	size := g.size.Load()
+	count := e.holdCount.Load()
+	if size == 0 || count == 0 {
+		return 0
+	}
+
+	pending, active := counterParts(g.counter.Load())
+	if pending == 0 && int(active) < int(size) {
+		return 0
+	}
+
+	// the call needs the pending n ahead of it, and its own 1, to be freed,
+	// and the held slots are freed at a rate of size per average hold.
+	avgHold := e.holdTotal.Load() / count
+	return time.Duration(avgHold * (int64(pending) + 1) / int64(size))
+}
+
+// SetMaxHoldDuration sets the maximum duration that a [Reservation] made by
+// [Group.Acquire] can be held for, after which its n is forcibly freed, and
+// the provided onReclaim func, if not nil, is called with its n and how
+// long it was held, which is at least d.
+//
+// It's a last resort against the reservations that are never released,
+// so a buggy holder can't consume the [Group]'s room forever.
+// A reclaimed [Reservation] is released, so a later [Reservation.Release]
+// call is a no-op, but its holder might still be using the resource while
+// the room is given to others.
+// The reservations made via the other reserve methods aren't affected, as
+// they have no handle to make their later free call a no-op.
+//
+// It only affects the [Group.Acquire] calls made after it's set, and each
+// of them watches its hold in a new goroutine, until it's released.
+// A d less than or equal to 0 disables it, which is the default.
+func (g *Group) SetMaxHoldDuration(d time.Duration, onReclaim func(n int, held time.Duration)) {
+	e := g.initExt()
+	if d <= 0 {
+		e.maxHold.Store(nil)
+		return
+	}
+	e.maxHold.Store(&maxHold{d: d, onReclaim: onReclaim})
+}
+
+// Block is a handle to a block of n reserved from a [Group], as returned by
+// [Group.ReserveBlock], which remembers its n, so the whole block is freed
+// at once, without repeating it.
+//
+// It's all-or-nothing, so there's no way to free part of the block.
+type Block struct {
+	g     *Group
+	n     int
+	freed atomic.Bool
+}
+
+// ReserveBlock reserves n, blocking if needed, exactly like
+// [Group.ReserveN], and returns a [Block] handle to free it via
+// [Block.Free].
+//
+// It returns false with a nil [Block] if the reservation wasn't made.
+//
+// It panics if n is less than or equal to 0, or greater than [math.MaxInt32].
+func (g *Group) ReserveBlock(doneChan <-chan struct{}, n int) (*Block, bool) {
+	if !g.reserveNWait(doneChan, n) {
+		return nil, false
+	}
+	return &Block{g: g, n: n}, true
+}
+
+// N returns the n held by the [Block].
+func (b *Block) N() int {
+	return b.n
+}
+
+// Free frees the whole n held by the [Block], exactly like [Group.FreeN].
+// It's safe to call multiple times, and frees the n exactly once.
+func (b *Block) Free() {
+	if !b.freed.CompareAndSwap(false, true) {
+		return
+	}
+	b.g.FreeN(b.n)
+}
+
+// Handle is a handle to the n reserved from a [Group], as returned by
+// [Group.AcquireHandle], which is like a [Reservation], but is a value,
+// whose state is recycled once it's released, so it's allocation-free.
+//
+// Its state carries a generation, which is advanced on release, so a copy
+// of a released [Handle] never affects the [Handle] that reuses its state.
+type Handle struct {
+	s   *handleState
+	gen uint64
+	n   int
+}
+
+// handleState is the recycled state of a [Handle].
+type handleState struct {
+	g          *Group
+	n          int
+	acquiredAt time.Time
+	gen        atomic.Uint64
+}
+
+var handleStatePool = sync.Pool{
+	New: func() any { return new(handleState) },
+}
+
+// AcquireHandle reserves n, blocking if needed, exactly like
+// [Group.ReserveN], and returns a [Handle] to free it via [Handle.Release].
+//
+// Unlike [Group.Acquire], it doesn't allocate, as the state of the [Handle]
+// is drawn from a pool, and recycled on release, which suits the hot paths.
+// So the [Reservation] features that need the handle to outlive its
+// release, which are the [Group.EnableAcquireTracking] stacks and the
+// [Group.SetMaxHoldDuration] limit, don't apply to it.
+// But its hold time is reported by [Group.EstimatedWait] too.
+//
+// It returns false with a zero [Handle] if the reservation wasn't made.
+//
+// It panics if n is less than or equal to 0, or greater than [math.MaxInt32].
+func (g *Group) AcquireHandle(doneChan <-chan struct{}, n int) (Handle, bool) {
+	if !g.reserveNWait(doneChan, n) {
+		return Handle{}, false
+	}
+
+	s := handleStatePool.Get().(*handleState)
+	s.g = g
+	s.n = n
+	s.acquiredAt = g.now()
+	return Handle{s: s, gen: s.gen.Load(), n: n}, true
+}
+
+// N returns the n held by the [Handle], which is 0 for a zero [Handle].
+func (h Handle) N() int {
+	return h.n
+}
+
+// Release frees the n held by the [Handle], exactly like [Group.FreeN].
+// It's safe to call multiple times, on any copy of the [Handle], and frees
+// the n exactly once.
+// It's a no-op on a zero [Handle].
+func (h Handle) Release() {
+	s := h.s
+	if s == nil || !s.gen.CompareAndSwap(h.gen, h.gen+1) {
+		return
+	}
+
+	g, n := s.g, s.n
+	g.recordHold(g.now().Sub(s.acquiredAt))
+	s.g = nil
+	handleStatePool.Put(s)
+
+	g.FreeN(n)
+}
+
+// EnableAcquireTracking enables recording the stack of each [Group.Acquire]
+// call, until its [Reservation] is released, which is reported by
+// [Group.OutstandingStacks].
+//
+// It's meant for diagnosing the reservations that are never released, and
+// it's expensive, as it captures a stack on every [Group.Acquire] call.
+// It only affects the [Group.Acquire] calls made after it's enabled.
+func (g *Group) EnableAcquireTracking() {
+	g.initExt().acquireTracking.Store(true)
+}
+
+// OutstandingStacks returns the stacks of the [Group.Acquire] calls whose
+// reservations are still held, formatted like a goroutine trace, one per
+// held [Reservation], in no particular order.
+//
+// It returns nil if [Group.EnableAcquireTracking] wasn't called.
+func (g *Group) OutstandingStacks() []string {
+	e := g.ext.Load()
+	if e == nil {
+		return nil
+	}
+
+	e.trackedMu.Lock()
+	defer e.trackedMu.Unlock()
+
+	var stacks []string
+	for r := range e.tracked {
+		stacks = append(stacks, formatStack(r.n, r.stack))
+	}
+	return stacks
+}
+
+func (g *Group) track(r *Reservation) {
+	e := g.initExt()
+	e.trackedMu.Lock()
+	if e.tracked == nil {
+		e.tracked = make(map[*Reservation]struct{})
+	}
+	e.tracked[r] = struct{}{}
+	e.trackedMu.Unlock()
+}
+
+func (g *Group) untrack(r *Reservation) {
+	e := g.initExt()
+	e.trackedMu.Lock()
+	delete(e.tracked, r)
+	e.trackedMu.Unlock()
+}
+
+func formatStack(n int, pcs []uintptr) string {
+	var b strings.Builder
+	b.WriteString("reservation of ")
+	b.WriteString(strconv.Itoa(n))
+	b.WriteString(":\n")
+
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		b.WriteString(frame.Function)
+		b.WriteString("\n\t")
+		b.WriteString(frame.File)
+		b.WriteString(":")
+		b.WriteString(strconv.Itoa(frame.Line))
+		b.WriteString("\n")
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
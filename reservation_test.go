@@ -0,0 +1,229 @@
+package sema_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asmsh/sema"
+)
+
+func TestGroupAcquire(t *testing.T) {
+	t.Parallel()
+
+	sg := sema.NewGroup(3)
+
+	r, ok := sg.Acquire(nil, 2)
+	if !ok || r.N() != 2 {
+		t.Fatalf("Acquire should succeed with n 2")
+	}
+	if active := sg.ActiveCount(); active != 2 {
+		t.Fatalf("Group active count should be 2, got %d", active)
+	}
+
+	// release is idempotent.
+	r.Release()
+	r.Release()
+	if active := sg.ActiveCount(); active != 0 {
+		t.Fatalf("Group active count should be 0, got %d", active)
+	}
+
+	if r, ok := sg.Acquire(nil, 4); ok || r != nil {
+		t.Fatalf("Acquire should fail for n greater than the size")
+	}
+}
+
+func TestGroupAcquireHandle(t *testing.T) {
+	t.Parallel()
+
+	sg := sema.NewGroup(3)
+
+	h, ok := sg.AcquireHandle(nil, 2)
+	if !ok || h.N() != 2 {
+		t.Fatalf("AcquireHandle should succeed with n 2")
+	}
+	if active := sg.ActiveCount(); active != 2 {
+		t.Fatalf("Group active count should be 2, got %d", active)
+	}
+
+	// release is idempotent, on any copy of the handle.
+	stale := h
+	h.Release()
+	stale.Release()
+	if active := sg.ActiveCount(); active != 0 {
+		t.Fatalf("Group active count should be 0, got %d", active)
+	}
+
+	// a released handle never frees the n of a handle that reuses its state.
+	for range 100 {
+		h, _ := sg.AcquireHandle(nil, 1)
+		stale.Release()
+		if active := sg.ActiveCount(); active != 1 {
+			t.Fatalf("Group active count should be 1, got %d", active)
+		}
+		stale = h
+		h.Release()
+	}
+
+	var zero sema.Handle
+	zero.Release()
+
+	if h, ok := sg.AcquireHandle(nil, 4); ok || h.N() != 0 {
+		t.Fatalf("AcquireHandle should fail for n greater than the size")
+	}
+}
+
+func acquireLeaking(sg *sema.Group) *sema.Reservation {
+	r, _ := sg.Acquire(nil, 1)
+	return r
+}
+
+func TestGroupOutstandingStacks(t *testing.T) {
+	t.Parallel()
+
+	sg := sema.NewGroup(3)
+	if stacks := sg.OutstandingStacks(); stacks != nil {
+		t.Fatalf("OutstandingStacks should be nil without tracking, got: %v", stacks)
+	}
+
+	sg.EnableAcquireTracking()
+	leaked := acquireLeaking(sg)
+	r, _ := sg.Acquire(nil, 1)
+
+	stacks := sg.OutstandingStacks()
+	if len(stacks) != 2 {
+		t.Fatalf("OutstandingStacks should have 2 stacks, got: %v", stacks)
+	}
+
+	// releasing removes its stack, leaving only the leaking call site.
+	r.Release()
+	stacks = sg.OutstandingStacks()
+	if len(stacks) != 1 || !strings.Contains(stacks[0], "acquireLeaking") {
+		t.Fatalf("OutstandingStacks should only have the leaking stack, got: %v", stacks)
+	}
+
+	leaked.Release()
+	if stacks := sg.OutstandingStacks(); len(stacks) != 0 {
+		t.Fatalf("OutstandingStacks should be empty, got: %v", stacks)
+	}
+}
+
+func TestGroupReserveBlock(t *testing.T) {
+	t.Parallel()
+
+	sg := sema.NewGroup(10)
+
+	b, ok := sg.ReserveBlock(nil, 5)
+	if !ok || b.N() != 5 {
+		t.Fatalf("ReserveBlock should reserve a block of 5")
+	}
+	other, _ := sg.ReserveBlock(nil, 3)
+
+	if active := sg.ActiveCount(); active != 8 {
+		t.Fatalf("Group active count should be 8, got %d", active)
+	}
+
+	// the whole block is freed at once, and only once.
+	b.Free()
+	if active := sg.ActiveCount(); active != 3 {
+		t.Fatalf("Group active count should be 3, got %d", active)
+	}
+	b.Free()
+	if active := sg.ActiveCount(); active != 3 {
+		t.Fatalf("Group active count should stay 3 after a double free, got %d", active)
+	}
+
+	if b, ok := sg.ReserveBlock(nil, 11); ok || b != nil {
+		t.Fatalf("ReserveBlock should fail for n greater than the size")
+	}
+
+	other.Free()
+	sg.Wait()
+}
+
+func TestGroupFakeClockSetMaxHoldDuration(t *testing.T) {
+	t.Parallel()
+
+	sg := sema.NewGroup(2)
+	fc := newFakeClock(sg)
+
+	reclaimed := make(chan int, 1)
+	reclaimedHeld := make(chan time.Duration, 1)
+	sg.SetMaxHoldDuration(time.Second, func(n int, held time.Duration) {
+		reclaimed <- n
+		reclaimedHeld <- held
+	})
+
+	// a reservation released in time isn't reclaimed.
+	r, _ := sg.Acquire(nil, 1)
+	fc.WaitTimers(1)
+	r.Release()
+	fc.WaitNoTimers()
+
+	// the held duration is measured on the fake clock when it's reclaimed,
+	// so it's past the limit when the clock is advanced past it at once.
+	stuck, _ := sg.Acquire(nil, 2)
+	fc.WaitTimers(1)
+	fc.Advance(1500 * time.Millisecond)
+
+	if n := <-reclaimed; n != 2 {
+		t.Fatalf("the stuck reservation of 2 should be reclaimed, got %d", n)
+	}
+	if held := <-reclaimedHeld; held != 1500*time.Millisecond {
+		t.Fatalf("the stuck reservation should be held for 1.5s, got %v", held)
+	}
+	sg.Wait()
+
+	// the later manual release is a no-op.
+	stuck.Release()
+	if active := sg.ActiveCount(); active != 0 {
+		t.Fatalf("Group active count should be 0, got %d", active)
+	}
+	select {
+	case n := <-reclaimed:
+		t.Fatalf("only the stuck reservation should be reclaimed, got %d", n)
+	default:
+	}
+}
+
+func TestGroupFakeClockEstimatedWait(t *testing.T) {
+	t.Parallel()
+
+	sg := sema.NewGroup(2)
+	fc := newFakeClock(sg)
+
+	if wait := sg.EstimatedWait(); wait != 0 {
+		t.Fatalf("EstimatedWait should be 0 without any hold times, got %v", wait)
+	}
+
+	// establish an average hold time of 1s.
+	r1, _ := sg.Acquire(nil, 1)
+	r2, _ := sg.Acquire(nil, 1)
+	fc.Advance(time.Second)
+	r1.Release()
+	r2.Release()
+
+	if wait := sg.EstimatedWait(); wait != 0 {
+		t.Fatalf("EstimatedWait should be 0 with room, got %v", wait)
+	}
+
+	// fill the Group, and queue 3 more calls.
+	sg.ReserveN(nil, 2)
+	for range 3 {
+		go func() {
+			sg.Reserve()
+			sg.Free()
+		}()
+	}
+	for sg.PendingCount() != 3 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// 4 slots must be freed, out of 2 that are freed every 1s.
+	if wait := sg.EstimatedWait(); wait < time.Second || wait > 3*time.Second {
+		t.Fatalf("EstimatedWait should be about 2s, got %v", wait)
+	}
+
+	sg.FreeN(2)
+	sg.Wait()
+}
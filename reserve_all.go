@@ -0,0 +1,115 @@
+// Copyright 2025 Ahmad Sameh(asmsh)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sema
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+	"unsafe"
+)
+
+// Request describes N resources to be reserved from a [Group],
+// as passed to [ReserveAll].
+type Request struct {
+	Group *Group
+	N     int
+}
+
+// ReserveAll reserves the N of every provided [Request] from its [Group],
+// blocking if needed, and returns true only if all of them were reserved.
+// Either all the requests are reserved, or none of them are.
+//
+// The groups are reserved from in a consistent global order, so concurrent
+// ReserveAll calls that share some groups can't deadlock each other.
+// Requests for the same [Group] are merged into a single [Group.ReserveN] call.
+//
+// If the provided doneChan becomes receive-ready before all the requests are
+// reserved, the ones reserved so far are freed, and it returns false.
+//
+// On success, the returned release func frees all the reserved resources.
+// Calling it more than once has no effect.
+// On failure, the returned release func is nil.
+//
+// It panics if any request has a nil [Group] or an N less than or equal to 0,
+// or if any merged N is greater than [math.MaxInt32], or than a non-zero
+// [Group.Size], exactly like [Group.ReserveN], so it returns false instead
+// if that [Group] was resized via [Group.Resize].
+// All the requests are checked before any of them is reserved, so a panic
+// never leaves some of them reserved.
+func ReserveAll(doneChan <-chan struct{}, reqs ...Request) (release func(), ok bool) {
+	// validate all the requests before reserving anything, so a panic can't
+	// leave some of them reserved.
+	sorted := make([]Request, 0, len(reqs))
+	for _, r := range reqs {
+		if r.Group == nil {
+			panic("sema.Group: nil group in reserve request")
+		}
+		r.Group.checkN(r.N, "reserve")
+		sorted = append(sorted, r)
+	}
+
+	// order the requests by the address of their groups, which is the
+	// consistent global order that all ReserveAll calls follow.
+	slices.SortFunc(sorted, func(a, b Request) int {
+		return cmp.Compare(uintptr(unsafe.Pointer(a.Group)), uintptr(unsafe.Pointer(b.Group)))
+	})
+
+	// merge the requests for the same group, as reserving from the same
+	// group twice could block on the first reservation.
+	merged := sorted[:0]
+	for _, r := range sorted {
+		if last := len(merged) - 1; last >= 0 && merged[last].Group == r.Group {
+			// check before adding, so the sum can't wrap past the range.
+			if merged[last].N > maxN-r.N {
+				r.Group.panic("group reserve N value out of range")
+			}
+			merged[last].N += r.N
+			continue
+		}
+		merged = append(merged, r)
+	}
+
+	// the merged N can exceed what any of its requests did.
+	for _, r := range merged {
+		if r.N > maxN {
+			r.Group.panic("group reserve N value out of range")
+		}
+		if r.Group.exceedsSize(r.N) && !r.Group.resized.Load() {
+			r.Group.panic("reserve N exceeds group size")
+		}
+	}
+
+	for i, r := range merged {
+		if !r.Group.reserveN(doneChan, r.N, nil).Granted() {
+			// free the ones that got reserved, in reverse order.
+			for j := i - 1; j >= 0; j-- {
+				merged[j].Group.FreeN(merged[j].N)
+			}
+			return nil, false
+		}
+	}
+
+	var once sync.Once
+	release = func() {
+		once.Do(func() {
+			for j := len(merged) - 1; j >= 0; j-- {
+				merged[j].Group.FreeN(merged[j].N)
+			}
+		})
+	}
+
+	return release, true
+}
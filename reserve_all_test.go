@@ -0,0 +1,162 @@
+package sema_test
+
+import (
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/asmsh/sema"
+)
+
+func TestReserveAll(t *testing.T) {
+	t.Parallel()
+
+	t.Run("reserves all the requests and releases them", func(t *testing.T) {
+		sg1 := sema.NewGroup(2)
+		sg2 := sema.NewGroup(3)
+
+		release, ok := sema.ReserveAll(nil,
+			sema.Request{Group: sg1, N: 1},
+			sema.Request{Group: sg2, N: 2},
+			sema.Request{Group: sg1, N: 1},
+		)
+		if !ok {
+			t.Fatalf("ReserveAll should succeed")
+		}
+		if active := sg1.ActiveCount(); active != 2 {
+			t.Errorf("Group active count should be 2, got %d", active)
+		}
+		if active := sg2.ActiveCount(); active != 2 {
+			t.Errorf("Group active count should be 2, got %d", active)
+		}
+
+		release()
+		release()
+		if active := sg1.ActiveCount(); active != 0 {
+			t.Errorf("Group active count should be 0, got %d", active)
+		}
+		if active := sg2.ActiveCount(); active != 0 {
+			t.Errorf("Group active count should be 0, got %d", active)
+		}
+	})
+
+	t.Run("reserves none of the requests if aborted", func(t *testing.T) {
+		sg1 := sema.NewGroup(2)
+		sg2 := sema.NewGroup(2)
+
+		// make sg2 full, so ReserveAll blocks on it.
+		sg2.ReserveN(nil, 2)
+
+		doneChan := make(chan struct{})
+		time.AfterFunc(10*time.Millisecond, func() { close(doneChan) })
+
+		release, ok := sema.ReserveAll(doneChan,
+			sema.Request{Group: sg1, N: 2},
+			sema.Request{Group: sg2, N: 1},
+		)
+		if ok || release != nil {
+			t.Fatalf("ReserveAll should fail")
+		}
+		if active := sg1.ActiveCount(); active != 0 {
+			t.Errorf("Group active count should be 0, got %d", active)
+		}
+		if active := sg2.ActiveCount(); active != 2 {
+			t.Errorf("Group active count should be 2, got %d", active)
+		}
+		if pending := sg2.PendingCount(); pending != 0 {
+			t.Errorf("Group pending count should be 0, got %d", pending)
+		}
+	})
+
+	t.Run("concurrent calls in opposite orders don't deadlock", func(t *testing.T) {
+		sg1 := sema.NewGroup(1)
+		sg2 := sema.NewGroup(1)
+
+		wg := sync.WaitGroup{}
+		for i := range 100 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				reqs := []sema.Request{{Group: sg1, N: 1}, {Group: sg2, N: 1}}
+				if i%2 == 0 {
+					reqs[0], reqs[1] = reqs[1], reqs[0]
+				}
+
+				release, ok := sema.ReserveAll(nil, reqs...)
+				if !ok {
+					t.Errorf("ReserveAll should succeed")
+					return
+				}
+				release()
+			}()
+		}
+		wg.Wait()
+
+		sg1.Wait()
+		sg2.Wait()
+	})
+	t.Run("panics before reserving if a merged N exceeds the size", func(t *testing.T) {
+		sg1 := sema.NewGroup(2)
+		sg2 := sema.NewGroup(2)
+
+		defer func() {
+			if r := recover(); r != "sema.Group: reserve N exceeds group size" {
+				t.Errorf("ReserveAll should panic, got %v", r)
+			}
+			if active := sg1.ActiveCount(); active != 0 {
+				t.Errorf("Group active count should be 0, got %d", active)
+			}
+			if active := sg2.ActiveCount(); active != 0 {
+				t.Errorf("Group active count should be 0, got %d", active)
+			}
+		}()
+
+		// each request fits, but the merged N of sg2 doesn't.
+		sema.ReserveAll(nil,
+			sema.Request{Group: sg1, N: 2},
+			sema.Request{Group: sg2, N: 2},
+			sema.Request{Group: sg2, N: 1},
+		)
+	})
+
+	t.Run("panics before reserving if a merged N is out of range", func(t *testing.T) {
+		sg1 := sema.NewGroup(2)
+		sg2 := sema.NewGroup(0)
+
+		defer func() {
+			if r := recover(); r != "sema.Group: group reserve N value out of range" {
+				t.Errorf("ReserveAll should panic, got %v", r)
+			}
+			if active := sg1.ActiveCount(); active != 0 {
+				t.Errorf("Group active count should be 0, got %d", active)
+			}
+		}()
+
+		sema.ReserveAll(nil,
+			sema.Request{Group: sg1, N: 1},
+			sema.Request{Group: sg2, N: math.MaxInt32},
+			sema.Request{Group: sg2, N: 1},
+		)
+	})
+
+	t.Run("panics before reserving if a merged N wraps", func(t *testing.T) {
+		sg := sema.NewNamedGroup("db", 2)
+
+		defer func() {
+			if r := recover(); r != `sema.Group "db": group reserve N value out of range` {
+				t.Errorf("ReserveAll should panic, got %v", r)
+			}
+			if active := sg.ActiveCount(); active != 0 {
+				t.Errorf("Group active count should be 0, got %d", active)
+			}
+		}()
+
+		// the sum of the N wraps to a negative value.
+		sema.ReserveAll(nil,
+			sema.Request{Group: sg, N: math.MaxInt},
+			sema.Request{Group: sg, N: 2},
+		)
+	})
+}
@@ -0,0 +1,178 @@
+// Copyright 2025 Ahmad Sameh(asmsh)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sema
+
+import (
+	"sync"
+)
+
+// onceCall is an in-flight reservation made via [Group.ReserveOnce].
+type onceCall struct {
+	// done is closed once the leader releases, or fails to reserve.
+	done chan struct{}
+
+	// reserved is set before done is closed.
+	reserved bool
+}
+
+// ReserveOnce reserves n on behalf of the provided key, blocking if needed,
+// exactly like [Group.ReserveN] with a nil doneChan, deduplicating the
+// concurrent calls with the same key.
+//
+// The first call for a key becomes the leader, which reserves n and returns
+// true with a release func that frees the reserved n.
+// Any call for the same key while the leader holds the reservation blocks
+// until the leader releases, then returns false without reserving anything,
+// with a release func that does nothing.
+// So only n is reserved per key, regardless of the number of calls.
+//
+// The release func is safe to call multiple times, and frees the reserved
+// n exactly once.
+//
+// If n is greater than the [Group.Size], the leader returns false with a nil
+// release func, and any blocked calls for the same key retry on their own.
+//
+// The key must be comparable, as it's used as a map key.
+//
+// It panics if n is less than or equal to 0, or greater than [math.MaxInt32].
+func (g *Group) ReserveOnce(key any, n int) (leader bool, release func()) {
+	e := g.initExt()
+	for {
+		e.onceMu.Lock()
+		if c := e.once[key]; c != nil {
+			e.onceMu.Unlock()
+
+			<-c.done
+			if !c.reserved {
+				// the leader couldn't reserve, so try on our own.
+				continue
+			}
+			return false, func() {}
+		}
+
+		c := &onceCall{done: make(chan struct{})}
+		if e.once == nil {
+			e.once = make(map[any]*onceCall)
+		}
+		e.once[key] = c
+		e.onceMu.Unlock()
+
+		c.reserved = g.reserveNWait(nil, n)
+
+		finish := func() {
+			e.onceMu.Lock()
+			delete(e.once, key)
+			e.onceMu.Unlock()
+
+			close(c.done)
+		}
+
+		if !c.reserved {
+			finish()
+			return false, nil
+		}
+
+		return true, sync.OnceFunc(func() {
+			finish()
+			g.FreeN(n)
+		})
+	}
+}
+
+// doCall is an in-flight init call made via [Group.DoOnce].
+type doCall struct {
+	// done is closed once the init func returns, or the reservation fails.
+	done chan struct{}
+
+	// val and err are set before done is closed.
+	val any
+	err error
+
+	// panicked is set if init panicked, with the value it panicked with
+	// in val, so the blocked calls panic with it too.
+	panicked bool
+}
+
+// DoOnce calls init on behalf of the provided key, while holding a
+// reservation of 1, deduplicating the concurrent calls with the same key.
+//
+// The first call for a key reserves 1, blocking if needed, exactly like
+// [Group.Reserve], then calls init and frees the reserved 1 once it returns.
+// Any call for the same key while init is running blocks until it returns,
+// then returns the same result, without reserving anything.
+// So calls for different keys run init concurrently, up to the
+// [Group.Size], while the calls for the same key share a single init call.
+//
+// The result isn't kept after init returns, so a later call for the same
+// key calls init again.
+//
+// If init panics, the call and any blocked calls for the same key panic
+// with the same value.
+//
+// If the reservation isn't admitted by the gate set via
+// [Group.SetAdmissionGate], the call and any blocked calls for the same key
+// return nil with [ErrNotAdmitted], without calling init.
+//
+// The key must be comparable, as it's used as a map key.
+func (g *Group) DoOnce(key any, init func() (any, error)) (any, error) {
+	e := g.initExt()
+	e.doMu.Lock()
+	if c := e.do[key]; c != nil {
+		e.doMu.Unlock()
+
+		<-c.done
+		if c.panicked {
+			panic(c.val)
+		}
+		return c.val, c.err
+	}
+
+	c := &doCall{done: make(chan struct{})}
+	if e.do == nil {
+		e.do = make(map[any]*doCall)
+	}
+	e.do[key] = c
+	e.doMu.Unlock()
+
+	defer func() {
+		// record the panic of init before the blocked calls are released,
+		// so they don't take the zero result as its result.
+		r := recover()
+		if r != nil {
+			c.val, c.panicked = r, true
+		}
+
+		e.doMu.Lock()
+		delete(e.do, key)
+		e.doMu.Unlock()
+
+		close(c.done)
+
+		if r != nil {
+			panic(r)
+		}
+	}()
+
+	// a call of 1 without a doneChan can only fail if it's not admitted,
+	// as it's never greater than the size, nor rejected for its weight.
+	if g.ReserveNStatus(nil, 1) == NotAdmitted {
+		c.err = ErrNotAdmitted
+		return nil, c.err
+	}
+	defer g.Free()
+
+	c.val, c.err = init()
+	return c.val, c.err
+}
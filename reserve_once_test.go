@@ -0,0 +1,185 @@
+package sema_test
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/asmsh/sema"
+)
+
+func TestGroupReserveOnce(t *testing.T) {
+	t.Parallel()
+
+	const callers = 10
+
+	sg := sema.NewGroup(4)
+
+	leader, release := sg.ReserveOnce("key", 2)
+	if !leader {
+		t.Fatalf("the first ReserveOnce call should be the leader")
+	}
+
+	wg := sync.WaitGroup{}
+	for range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, release := sg.ReserveOnce("key", 2)
+			defer release()
+
+			// at most a single reservation per key is held at any time.
+			if active := sg.ActiveCount(); active > 2 {
+				t.Errorf("Group active count should be at most 2, got %d", active)
+			}
+		}()
+	}
+
+	// a different key is reserved on its own.
+	otherLeader, otherRelease := sg.ReserveOnce("other", 1)
+	if !otherLeader {
+		t.Fatalf("the first ReserveOnce call for another key should be the leader")
+	}
+	otherRelease()
+
+	// let the callers block on the leader.
+	for range callers {
+		runtime.Gosched()
+	}
+
+	// only the leader's 2 are reserved, regardless of the callers.
+	if active := sg.ActiveCount(); active != 2 {
+		t.Fatalf("Group active count should be 2, got %d", active)
+	}
+
+	release()
+	release()
+	wg.Wait()
+
+	sg.Wait()
+
+	// a leader that can't reserve returns false with a nil release.
+	if leader, release := sg.ReserveOnce("key", 5); leader || release != nil {
+		t.Fatalf("ReserveOnce should fail for n greater than the size")
+	}
+}
+
+func TestGroupDoOnce(t *testing.T) {
+	t.Parallel()
+
+	const callers = 10
+
+	sg := sema.NewGroup(2)
+
+	var inits atomic.Int32
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+
+	wg := sync.WaitGroup{}
+	results := make([]any, callers)
+	for i := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := sg.DoOnce("key", func() (any, error) {
+				inits.Add(1)
+				close(started)
+				<-unblock
+				return "value", nil
+			})
+			if err != nil {
+				t.Errorf("DoOnce should succeed, got %v", err)
+			}
+			results[i] = v
+		}()
+	}
+
+	<-started
+
+	// the other keys share the Group's limit with the running init.
+	var maxActive atomic.Int32
+	keysWg := sync.WaitGroup{}
+	for i := range callers {
+		keysWg.Add(1)
+		go func() {
+			defer keysWg.Done()
+			_, _ = sg.DoOnce(i, func() (any, error) {
+				active := int32(sg.ActiveCount())
+				for {
+					m := maxActive.Load()
+					if active <= m || maxActive.CompareAndSwap(m, active) {
+						break
+					}
+				}
+				return nil, nil
+			})
+		}()
+	}
+	keysWg.Wait()
+
+	if m := maxActive.Load(); m > 2 {
+		t.Fatalf("Group active count should be at most 2, got %d", m)
+	}
+
+	close(unblock)
+	wg.Wait()
+
+	if n := inits.Load(); n != 1 {
+		t.Fatalf("init should be called once, got %d", n)
+	}
+	for i, v := range results {
+		if v != "value" {
+			t.Fatalf("caller %d should get the shared value, got %v", i, v)
+		}
+	}
+
+	// the error is shared, and the result isn't kept after init returns.
+	wantErr := errors.New("init failed")
+	if _, err := sg.DoOnce("key", func() (any, error) { return nil, wantErr }); err != wantErr {
+		t.Fatalf("DoOnce should return the init error, got %v", err)
+	}
+
+	sg.Wait()
+}
+
+func TestGroupDoOncePanic(t *testing.T) {
+	t.Parallel()
+
+	sg := sema.NewGroup(1)
+
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+
+	recovered := make(chan any, 2)
+	call := func(init func() (any, error)) {
+		defer func() { recovered <- recover() }()
+		_, _ = sg.DoOnce("key", init)
+	}
+
+	go call(func() (any, error) {
+		close(started)
+		<-unblock
+		panic("init failed")
+	})
+	<-started
+
+	// the blocked call gets the panic of the running init.
+	go call(func() (any, error) {
+		t.Errorf("init should be called once")
+		return nil, nil
+	})
+	time.Sleep(10 * time.Millisecond)
+	close(unblock)
+
+	for range 2 {
+		if r := <-recovered; r != "init failed" {
+			t.Fatalf("DoOnce should panic with the init panic, got %v", r)
+		}
+	}
+
+	// the reserved 1 is freed despite the panic.
+	sg.Wait()
+}
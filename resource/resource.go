@@ -0,0 +1,120 @@
+// Copyright 2025 Ahmad Sameh(asmsh)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package resource gates the creation of resources, such as database
+// connections, by the room of a [sema.Group], so a resource is only
+// created while a slot is reserved for it.
+//
+// The reserved slot is freed if the resource can't be created, so a failed
+// creation never leaks a slot.
+package resource
+
+import (
+	"context"
+	"sync"
+
+	"github.com/asmsh/sema"
+)
+
+// Budget creates the resources of type T within the room of a [sema.Group],
+// reserving 1 for each created resource until it's released.
+type Budget[T any] struct {
+	g           *sema.Group
+	create      func(ctx context.Context) (T, error)
+	destroy     func(res T)
+	maxAttempts int
+}
+
+// NewBudget creates a new [Budget] that creates its resources via the
+// provided create func, within the room of g, and destroys them via the
+// provided destroy func, if not nil, once they're released.
+//
+// It panics if g or create is nil.
+func NewBudget[T any](
+	g *sema.Group,
+	create func(ctx context.Context) (T, error),
+	destroy func(res T),
+) *Budget[T] {
+	if g == nil {
+		panic("resource.Budget: nil group")
+	}
+	if create == nil {
+		panic("resource.Budget: nil create func")
+	}
+
+	return &Budget[T]{g: g, create: create, destroy: destroy, maxAttempts: 1}
+}
+
+// SetMaxAttempts sets the number of times that [Budget.Acquire] calls the
+// create func, while holding the reserved slot, before it gives up and
+// returns the last error, which is 1 by default, so it doesn't retry.
+//
+// An n less than 1 is treated as 1.
+func (b *Budget[T]) SetMaxAttempts(n int) {
+	b.maxAttempts = max(n, 1)
+}
+
+// Acquire reserves a slot from the [sema.Group], blocking if needed, exactly
+// like [sema.Group.ReserveN] with ctx.Done(), then creates a resource,
+// retrying up to the max attempts set via [Budget.SetMaxAttempts].
+//
+// On success, it returns the resource with a release func that destroys it,
+// then frees its slot.
+// The release func is safe to call multiple times, and does that only once.
+//
+// If the resource can't be created, or ctx is done before that, it frees
+// the reserved slot before returning the error.
+// It returns ctx.Err() if ctx is done before the slot is reserved,
+// [sema.ErrReserveTooLarge] if the [sema.Group] has no room at all, and
+// [sema.ErrMaxPendingWeight] if the reservation was rejected by
+// [sema.Group.SetMaxPendingWeight], and [sema.ErrNotAdmitted] if it wasn't
+// admitted by [sema.Group.SetAdmissionGate].
+func (b *Budget[T]) Acquire(ctx context.Context) (res T, release func(), err error) {
+	switch b.g.ReserveNStatus(ctx.Done(), 1) {
+	case sema.Impossible:
+		return res, nil, sema.ErrReserveTooLarge
+	case sema.Rejected:
+		return res, nil, sema.ErrMaxPendingWeight
+	case sema.NotAdmitted:
+		return res, nil, sema.ErrNotAdmitted
+	case sema.Aborted:
+		return res, nil, ctx.Err()
+	}
+
+	for range b.maxAttempts {
+		if err = ctx.Err(); err != nil {
+			break
+		}
+
+		res, err = b.create(ctx)
+		if err == nil {
+			return res, b.releaseFunc(res), nil
+		}
+	}
+
+	// the slot was never used, so don't leak it.
+	b.g.Free()
+
+	var zero T
+	return zero, nil, err
+}
+
+func (b *Budget[T]) releaseFunc(res T) func() {
+	return sync.OnceFunc(func() {
+		if b.destroy != nil {
+			b.destroy(res)
+		}
+		b.g.Free()
+	})
+}
@@ -0,0 +1,77 @@
+package resource_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/asmsh/sema"
+	"github.com/asmsh/sema/resource"
+)
+
+func TestBudgetAcquire(t *testing.T) {
+	sg := sema.NewGroup(2)
+
+	destroyed := 0
+	b := resource.NewBudget(sg, func(ctx context.Context) (int, error) {
+		return sg.ActiveCount(), nil
+	}, func(res int) {
+		destroyed++
+	})
+
+	// the resource is created while its slot is reserved.
+	res, release, err := b.Acquire(context.Background())
+	if err != nil || res != 1 {
+		t.Fatalf("Acquire should create the resource within a slot, got %d, %v", res, err)
+	}
+
+	release()
+	release()
+	if destroyed != 1 {
+		t.Fatalf("the resource should be destroyed once, got %d", destroyed)
+	}
+	sg.Wait()
+}
+
+func TestBudgetAcquireFailure(t *testing.T) {
+	sg := sema.NewGroup(1)
+
+	wantErr := errors.New("dial failed")
+	attempts := 0
+	b := resource.NewBudget(sg, func(ctx context.Context) (*struct{}, error) {
+		attempts++
+		if attempts < 3 {
+			return nil, wantErr
+		}
+		return &struct{}{}, nil
+	}, nil)
+
+	// a failed creation frees its slot before returning the error.
+	if _, release, err := b.Acquire(context.Background()); err != wantErr || release != nil {
+		t.Fatalf("Acquire should return the creation error, got %v", err)
+	}
+	if active := sg.ActiveCount(); active != 0 {
+		t.Fatalf("the failed creation should free its slot, got %d active", active)
+	}
+
+	// the creation is retried while holding the same slot.
+	b.SetMaxAttempts(2)
+	res, release, err := b.Acquire(context.Background())
+	if err != nil || res == nil {
+		t.Fatalf("Acquire should succeed on the retry, got %v", err)
+	}
+	if attempts != 3 {
+		t.Fatalf("the creation should be attempted 3 times in total, got %d", attempts)
+	}
+	release()
+
+	// a canceled ctx doesn't reserve anything.
+	sg.Reserve()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := b.Acquire(ctx); err != context.Canceled {
+		t.Fatalf("Acquire should return ctx.Err(), got %v", err)
+	}
+	sg.Free()
+	sg.Wait()
+}
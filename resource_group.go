@@ -0,0 +1,164 @@
+// Copyright 2025 Ahmad Sameh(asmsh)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sema
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+)
+
+// ErrResourceTooLarge is returned when the requested bytes are greater than
+// the bytes of a [ResourceGroup], so the reservation can never succeed.
+var ErrResourceTooLarge = errors.New("sema.ResourceGroup: reserve bytes exceed group bytes")
+
+// ResourceGroup is a two-dimensional semaphore, where each reservation takes
+// a slot, and a number of bytes, and it's admitted only if there's room for
+// both, like for a memory-bounded worker pool.
+//
+// The blocked reservations are queued in order, and on every release, only
+// the ones that fit both dimensions are woken up, in that order.
+// So a reservation of many bytes can be overtaken by smaller ones that fit
+// while it doesn't.
+type ResourceGroup struct {
+	maxSlots int
+	maxBytes int
+
+	// slots and bytes are the reserved amounts, and waiters are the blocked
+	// reservations, in order, all guarded by mu.
+	mu      sync.Mutex
+	slots   int
+	bytes   int
+	waiters []*resourceWaiter
+}
+
+// resourceWaiter is a blocked reservation of a [ResourceGroup], whose ready
+// chan is closed once its slot and bytes are reserved for it.
+type resourceWaiter struct {
+	bytes int
+	ready chan struct{}
+}
+
+// NewResourceGroup creates a new [ResourceGroup] with the provided numbers
+// of slots and bytes.
+//
+// It panics if slots is less than or equal to 0, or if bytes is less than 0.
+func NewResourceGroup(slots, bytes int) *ResourceGroup {
+	if slots <= 0 {
+		panic("sema.ResourceGroup: invalid slots value")
+	}
+	if bytes < 0 {
+		panic("sema.ResourceGroup: invalid bytes value")
+	}
+	return &ResourceGroup{maxSlots: slots, maxBytes: bytes}
+}
+
+// Reserve reserves a slot and the provided number of bytes, blocking until
+// there's room for both, or ctx is done.
+//
+// On success, it returns a release func that frees both, which is safe to
+// call multiple times, and frees them exactly once.
+// It returns ctx.Err() if ctx is done before the reservation is made, even
+// if there's room for it, and [ErrResourceTooLarge] if bytes is greater than
+// the bytes of the [ResourceGroup].
+//
+// It panics if bytes is less than 0.
+func (rg *ResourceGroup) Reserve(ctx context.Context, bytes int) (release func(), err error) {
+	if bytes < 0 {
+		panic("sema.ResourceGroup: invalid reserve bytes value")
+	}
+
+	if bytes > rg.maxBytes {
+		return nil, ErrResourceTooLarge
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	rg.mu.Lock()
+	if rg.fits(bytes) {
+		rg.slots++
+		rg.bytes += bytes
+		rg.mu.Unlock()
+
+		return sync.OnceFunc(func() { rg.release(bytes) }), nil
+	}
+
+	w := &resourceWaiter{bytes: bytes, ready: make(chan struct{})}
+	rg.waiters = append(rg.waiters, w)
+	rg.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return sync.OnceFunc(func() { rg.release(bytes) }), nil
+	case <-ctx.Done():
+	}
+
+	rg.mu.Lock()
+	select {
+	case <-w.ready:
+		// it was reserved while ctx was done, so free it, as the caller
+		// won't, which might wake up the waiters that fit now.
+		rg.mu.Unlock()
+		rg.release(bytes)
+	default:
+		rg.waiters = slices.DeleteFunc(rg.waiters, func(rw *resourceWaiter) bool {
+			return rw == w
+		})
+		rg.mu.Unlock()
+	}
+	return nil, ctx.Err()
+}
+
+// fits reports whether there's room for a slot and the provided bytes.
+// it must be called while holding the mutex.
+func (rg *ResourceGroup) fits(bytes int) bool {
+	return rg.slots < rg.maxSlots && rg.bytes+bytes <= rg.maxBytes
+}
+
+func (rg *ResourceGroup) release(bytes int) {
+	rg.mu.Lock()
+	rg.slots--
+	rg.bytes -= bytes
+
+	// wake up the blocked reservations that fit now, in order, reserving
+	// for them before they're woken up, so they can't be overtaken.
+	rg.waiters = slices.DeleteFunc(rg.waiters, func(w *resourceWaiter) bool {
+		if !rg.fits(w.bytes) {
+			return false
+		}
+		rg.slots++
+		rg.bytes += w.bytes
+		close(w.ready)
+		return true
+	})
+	rg.mu.Unlock()
+}
+
+// Slots returns the number of the reserved slots.
+func (rg *ResourceGroup) Slots() int {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+	return rg.slots
+}
+
+// Bytes returns the number of the reserved bytes.
+func (rg *ResourceGroup) Bytes() int {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+	return rg.bytes
+}
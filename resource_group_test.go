@@ -0,0 +1,131 @@
+package sema_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/asmsh/sema"
+)
+
+func TestResourceGroup(t *testing.T) {
+	t.Parallel()
+
+	rg := sema.NewResourceGroup(4, 100)
+
+	small, err := rg.Reserve(context.Background(), 60)
+	if err != nil {
+		t.Fatalf("Reserve should succeed within both budgets, got %v", err)
+	}
+
+	// a large-byte task waits for byte headroom, even though slots are free.
+	reserved := make(chan func())
+	go func() {
+		release, err := rg.Reserve(context.Background(), 50)
+		if err != nil {
+			t.Errorf("Reserve should succeed once there's byte headroom, got %v", err)
+		}
+		reserved <- release
+	}()
+	select {
+	case <-reserved:
+		t.Fatalf("Reserve should block without byte headroom")
+	case <-time.After(10 * time.Millisecond):
+	}
+	if slots := rg.Slots(); slots != 1 {
+		t.Fatalf("ResourceGroup slots should be 1, got %d", slots)
+	}
+
+	small()
+	small()
+	large := <-reserved
+	if bytes := rg.Bytes(); bytes != 50 {
+		t.Fatalf("ResourceGroup bytes should be 50, got %d", bytes)
+	}
+
+	// and a small task waits for a slot, even though there are free bytes.
+	var releases []func()
+	for range 3 {
+		release, _ := rg.Reserve(context.Background(), 1)
+		releases = append(releases, release)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := rg.Reserve(ctx, 1); err != context.DeadlineExceeded {
+		t.Fatalf("Reserve should block without a free slot, got %v", err)
+	}
+
+	if _, err := rg.Reserve(context.Background(), 101); err != sema.ErrResourceTooLarge {
+		t.Fatalf("Reserve should fail for bytes greater than the budget, got %v", err)
+	}
+
+	large()
+	for _, release := range releases {
+		release()
+	}
+	if slots, bytes := rg.Slots(), rg.Bytes(); slots != 0 || bytes != 0 {
+		t.Fatalf("ResourceGroup should be empty, got %d slots and %d bytes", slots, bytes)
+	}
+}
+
+func TestResourceGroupCanceled(t *testing.T) {
+	t.Parallel()
+
+	rg := sema.NewResourceGroup(1, 100)
+
+	// a done ctx fails, even if there's room for the reservation.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := rg.Reserve(ctx, 1); err != context.Canceled {
+		t.Fatalf("Reserve should fail with a done ctx, got %v", err)
+	}
+	if slots := rg.Slots(); slots != 0 {
+		t.Fatalf("ResourceGroup slots should be 0, got %d", slots)
+	}
+}
+
+func TestResourceGroupWakeupFits(t *testing.T) {
+	t.Parallel()
+
+	rg := sema.NewResourceGroup(2, 100)
+
+	bytesHeld, _ := rg.Reserve(context.Background(), 60)
+	slotHeld, _ := rg.Reserve(context.Background(), 0)
+
+	// queue a reservation that won't fit after the slot is released, and
+	// then one that will.
+	large := make(chan func())
+	go func() {
+		release, _ := rg.Reserve(context.Background(), 50)
+		large <- release
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	small := make(chan func())
+	go func() {
+		release, _ := rg.Reserve(context.Background(), 10)
+		small <- release
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	// only the small reservation is woken up, as the large one still
+	// doesn't fit the bytes.
+	slotHeld()
+	releaseSmall := <-small
+	select {
+	case <-large:
+		t.Fatalf("Reserve should block without byte headroom")
+	case <-time.After(10 * time.Millisecond):
+	}
+	if slots, bytes := rg.Slots(), rg.Bytes(); slots != 2 || bytes != 70 {
+		t.Fatalf("ResourceGroup should have 2 slots and 70 bytes, got %d slots and %d bytes", slots, bytes)
+	}
+
+	// and then the large one fits.
+	releaseSmall()
+	bytesHeld()
+	(<-large)()
+	if slots, bytes := rg.Slots(), rg.Bytes(); slots != 0 || bytes != 0 {
+		t.Fatalf("ResourceGroup should be empty, got %d slots and %d bytes", slots, bytes)
+	}
+}
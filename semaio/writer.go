@@ -0,0 +1,45 @@
+// Copyright 2025 Ahmad Sameh(asmsh)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package semaio gates I/O by the room of a [sema.Group], so the concurrent
+// I/O calls on a shared sink are bounded by its size.
+package semaio
+
+import (
+	"io"
+
+	"github.com/asmsh/sema"
+)
+
+// WrapWriter returns an [io.Writer] that reserves 1 from g around each Write
+// call to w, blocking if needed, exactly like [sema.Group.Reserve], so at
+// most the [sema.Group.Size] Write calls to w run concurrently.
+//
+// The returned writer returns whatever w returns, and frees the reserved 1
+// even if w panics.
+func WrapWriter(g *sema.Group, w io.Writer) io.Writer {
+	return &writer{g: g, w: w}
+}
+
+type writer struct {
+	g *sema.Group
+	w io.Writer
+}
+
+func (w *writer) Write(p []byte) (n int, err error) {
+	w.g.Reserve()
+	defer w.g.Free()
+
+	return w.w.Write(p)
+}
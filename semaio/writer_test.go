@@ -0,0 +1,88 @@
+package semaio_test
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/asmsh/sema"
+	"github.com/asmsh/sema/semaio"
+)
+
+// writerFunc is an io.Writer that calls itself on Write.
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
+
+func TestWrapWriter(t *testing.T) {
+	const (
+		size    = 3
+		writers = 20
+	)
+
+	sg := sema.NewGroup(size)
+
+	var running, maxRunning atomic.Int32
+	w := semaio.WrapWriter(sg, writerFunc(func(p []byte) (int, error) {
+		cur := running.Add(1)
+		defer running.Add(-1)
+		for {
+			m := maxRunning.Load()
+			if cur <= m || maxRunning.CompareAndSwap(m, cur) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		return len(p), nil
+	}))
+
+	wg := sync.WaitGroup{}
+	for range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if n, err := w.Write([]byte("data")); n != 4 || err != nil {
+				t.Errorf("Write should succeed, got %d, %v", n, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if m := maxRunning.Load(); m > size {
+		t.Fatalf("at most %d Write calls should run concurrently, got %d", size, m)
+	}
+	sg.Wait()
+}
+
+func TestWrapWriterFailure(t *testing.T) {
+	sg := sema.NewGroup(1)
+
+	// the error of the wrapped writer is returned.
+	wantErr := errors.New("sink closed")
+	w := semaio.WrapWriter(sg, writerFunc(func(p []byte) (int, error) {
+		return 1, wantErr
+	}))
+	if n, err := w.Write([]byte("data")); n != 1 || err != wantErr {
+		t.Fatalf("Write should return the sink's result, got %d, %v", n, err)
+	}
+
+	// the reserved slot is freed even if the wrapped writer panics.
+	w = semaio.WrapWriter(sg, writerFunc(func(p []byte) (int, error) {
+		panic("sink panicked")
+	}))
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("Write should propagate the panic")
+			}
+		}()
+		_, _ = w.Write([]byte("data"))
+	}()
+	if active := sg.ActiveCount(); active != 0 {
+		t.Fatalf("the slot should be freed after a panic, got %d active", active)
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2025 Ahmad Sameh(asmsh)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sema
+
+import (
+	"context"
+	"sync"
+)
+
+// ShardedGroups is a fixed set of [Group] shards, where each key is always
+// mapped to the same shard, providing coarse per-key concurrency limits
+// without keeping any per-key state.
+//
+// Keys that map to the same shard share its limit.
+type ShardedGroups struct {
+	shards []Group
+}
+
+// NewShardedGroups creates a new [ShardedGroups] with the provided number of
+// shards, each with the provided size.
+//
+// It panics if shards is less than or equal to 0.
+func NewShardedGroups(shards, sizePerShard int) *ShardedGroups {
+	if shards <= 0 {
+		panic("sema.ShardedGroups: invalid shards count")
+	}
+
+	sgs := &ShardedGroups{shards: make([]Group, shards)}
+	for i := range sgs.shards {
+		sgs.shards[i].setSize(sizePerShard)
+	}
+	return sgs
+}
+
+// Shard returns the [Group] shard that the provided key maps to.
+func (sgs *ShardedGroups) Shard(key string) *Group {
+	return &sgs.shards[sgs.shardIndex(key)]
+}
+
+func (sgs *ShardedGroups) shardIndex(key string) int {
+	// FNV-1a, inlined to avoid converting the key to a byte slice.
+	h := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= 16777619
+	}
+	return int(h % uint32(len(sgs.shards)))
+}
+
+// ReserveKey reserves n from the shard that the provided key maps to,
+// blocking if needed, exactly like [Group.ReserveN].
+//
+// On success, it returns a release func that frees the reserved n, which is
+// safe to call multiple times, and frees it exactly once.
+// It fails with the same errors as [Group.ReserveNContext], so it returns
+// [ErrReserveTooLarge] if n is greater than the shard size.
+//
+// It panics if n is less than or equal to 0, or greater than [math.MaxInt32].
+func (sgs *ShardedGroups) ReserveKey(ctx context.Context, key string, n int) (release func(), err error) {
+	g := sgs.Shard(key)
+
+	if err := g.ReserveNContext(ctx, n); err != nil {
+		return nil, err
+	}
+
+	return sync.OnceFunc(func() { g.FreeN(n) }), nil
+}
+
+// ShardStats returns the [Stats] of each shard, in shard order.
+func (sgs *ShardedGroups) ShardStats() []Stats {
+	stats := make([]Stats, len(sgs.shards))
+	for i := range sgs.shards {
+		stats[i] = sgs.shards[i].Stats()
+	}
+	return stats
+}
@@ -0,0 +1,78 @@
+package sema_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/asmsh/sema"
+)
+
+func TestShardedGroups(t *testing.T) {
+	t.Parallel()
+
+	t.Run("the same key always maps to the same shard", func(t *testing.T) {
+		sgs := sema.NewShardedGroups(8, 4)
+
+		for i := range 100 {
+			key := fmt.Sprintf("key-%d", i)
+			shard := sgs.Shard(key)
+			for range 10 {
+				if sgs.Shard(key) != shard {
+					t.Fatalf("key %q mapped to different shards", key)
+				}
+			}
+		}
+	})
+
+	t.Run("the reservation is made on the key's shard", func(t *testing.T) {
+		sgs := sema.NewShardedGroups(8, 4)
+
+		release, err := sgs.ReserveKey(context.Background(), "downloads", 3)
+		if err != nil {
+			t.Fatalf("ReserveKey should succeed, got %v", err)
+		}
+		if active := sgs.Shard("downloads").ActiveCount(); active != 3 {
+			t.Errorf("shard active count should be 3, got %d", active)
+		}
+
+		total := 0
+		for _, s := range sgs.ShardStats() {
+			if s.Size != 4 {
+				t.Errorf("shard size should be 4, got %d", s.Size)
+			}
+			total += s.Active
+		}
+		if total != 3 {
+			t.Errorf("total active count should be 3, got %d", total)
+		}
+
+		// the release func frees the n exactly once.
+		release()
+		release()
+		if active := sgs.Shard("downloads").ActiveCount(); active != 0 {
+			t.Errorf("shard active count should be 0, got %d", active)
+		}
+	})
+
+	t.Run("the reservation fails with the ctx error", func(t *testing.T) {
+		sgs := sema.NewShardedGroups(2, 1)
+
+		release, err := sgs.ReserveKey(context.Background(), "a", 1)
+		if err != nil {
+			t.Fatalf("ReserveKey should succeed, got %v", err)
+		}
+		defer release()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		if _, err := sgs.ReserveKey(ctx, "a", 1); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("ReserveKey should fail with %v, got %v", context.DeadlineExceeded, err)
+		}
+		if _, err := sgs.ReserveKey(context.Background(), "a", 2); !errors.Is(err, sema.ErrReserveTooLarge) {
+			t.Errorf("ReserveKey should fail with %v, got %v", sema.ErrReserveTooLarge, err)
+		}
+	})
+}
@@ -0,0 +1,55 @@
+// Copyright 2025 Ahmad Sameh(asmsh)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package signal stops the reservations of a [sema.Group] on OS signals.
+//
+// It's intended for main packages, such as CLI tools and servers, as
+// libraries shouldn't take over the handling of the process signals.
+package signal
+
+import (
+	"context"
+	"os"
+	ossignal "os/signal"
+	"syscall"
+
+	"github.com/asmsh/sema"
+)
+
+// ReserveUntilSignal returns a context that's canceled once any of the
+// provided signals arrives, to be used as the done source of the reserve
+// calls of g, so g stops admitting new work on that signal, and can then
+// be drained.
+//
+// If no signal is provided, it's canceled on [os.Interrupt] or
+// [syscall.SIGTERM] only, as the other signals, like a terminal resize,
+// aren't meant to stop the process.
+//
+// The signals are captured, via [ossignal.NotifyContext], until g drains
+// to zero after the context is canceled, so a repeated signal doesn't
+// kill the process while the admitted work completes.
+// After that, the default handling of the signals is restored, and a
+// repeated signal behaves as usual.
+func ReserveUntilSignal(g *sema.Group, sig ...os.Signal) context.Context {
+	if len(sig) == 0 {
+		sig = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+
+	ctx, stop := ossignal.NotifyContext(context.Background(), sig...)
+	context.AfterFunc(ctx, func() {
+		g.OnBatchComplete(stop)
+	})
+
+	return ctx
+}
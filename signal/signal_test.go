@@ -0,0 +1,34 @@
+package signal_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/asmsh/sema"
+	"github.com/asmsh/sema/signal"
+)
+
+func TestReserveUntilSignal(t *testing.T) {
+	sg := sema.NewGroup(1)
+	ctx := signal.ReserveUntilSignal(sg, os.Interrupt)
+
+	if !sg.ReserveN(ctx.Done(), 1) {
+		t.Fatalf("ReserveN should succeed before the signal")
+	}
+
+	p, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatalf("FindProcess failed: %v", err)
+	}
+	if err := p.Signal(os.Interrupt); err != nil {
+		t.Skipf("can't send an interrupt to the process: %v", err)
+	}
+	<-ctx.Done()
+
+	// new reservations are aborted, while the admitted one drains.
+	if sg.ReserveN(ctx.Done(), 1) {
+		t.Fatalf("ReserveN should be aborted after the signal")
+	}
+	sg.Free()
+	sg.Wait()
+}
@@ -0,0 +1,43 @@
+//go:build unix
+
+package signal_test
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/asmsh/sema"
+	"github.com/asmsh/sema/signal"
+)
+
+func TestReserveUntilSignalDefault(t *testing.T) {
+	sg := sema.NewGroup(1)
+	ctx := signal.ReserveUntilSignal(sg)
+
+	p, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatalf("FindProcess failed: %v", err)
+	}
+
+	// a terminal resize isn't an interrupt, so it's not relayed.
+	if err := p.Signal(syscall.SIGWINCH); err != nil {
+		t.Skipf("can't send a signal to the process: %v", err)
+	}
+	select {
+	case <-ctx.Done():
+		t.Fatalf("context should not be canceled by SIGWINCH")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := p.Signal(syscall.SIGTERM); err != nil {
+		t.Skipf("can't send a signal to the process: %v", err)
+	}
+	<-ctx.Done()
+
+	if sg.ReserveN(ctx.Done(), 1) {
+		t.Fatalf("ReserveN should be aborted after the signal")
+	}
+	sg.Wait()
+}
@@ -0,0 +1,131 @@
+// Copyright 2025 Ahmad Sameh(asmsh)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sema
+
+import (
+	"context"
+	"sync"
+)
+
+// Ticket is a reservation of N resources that's submitted to a [Group] via
+// [Group.Submit], and is reserved in the background.
+//
+// A Ticket is either pending, granted, or withdrawn.
+type Ticket struct {
+	g *Group
+	n int
+
+	// cancelChan is the doneChan of the background ReserveN call.
+	cancelChan chan struct{}
+	cancelOnce sync.Once
+
+	// doneChan is closed once the reservation is either granted or withdrawn,
+	// after setting the granted field.
+	doneChan chan struct{}
+	granted  bool
+
+	freeOnce sync.Once
+}
+
+// Submit submits a reservation of n resources from the [Group] and returns
+// immediately with a [Ticket] that can be used to await, cancel, or release
+// the reservation later.
+//
+// If the reservation can't be made right away, it's made in the background,
+// exactly like a [Group.ReserveN] call, which means it increments the
+// [Group.PendingCount] until the reservation is granted or the [Ticket] is
+// canceled.
+// Note: the [Group.PendingCount] might not include n right after it returns,
+// as the background call might not have started yet.
+//
+// The [Ticket] must be either canceled or released once it's no longer needed,
+// otherwise the reserved resources are never freed.
+//
+// It panics if n is less than or equal to 0, or greater than [math.MaxInt32],
+// or greater than a non-zero [Group.Size], exactly like [Group.ReserveN].
+func (g *Group) Submit(n int) *Ticket {
+	g.checkN(n, "reserve")
+
+	t := &Ticket{
+		g:          g,
+		n:          n,
+		cancelChan: make(chan struct{}),
+		doneChan:   make(chan struct{}),
+	}
+
+	// avoid starting a goroutine if the reservation can be made right away.
+	if g.TryReserveN(n) {
+		t.granted = true
+		close(t.doneChan)
+		return t
+	}
+
+	go func() {
+		t.granted = g.reserveNWait(t.cancelChan, n)
+		close(t.doneChan)
+	}()
+
+	return t
+}
+
+// Wait blocks until the [Ticket] is granted or canceled, and returns true if
+// it was granted.
+// It returns false if the provided ctx is done before that, which doesn't
+// cancel the [Ticket], and Wait can be called again later.
+func (t *Ticket) Wait(ctx context.Context) bool {
+	// prioritize the ticket result over the ctx, if both are ready.
+	select {
+	case <-t.doneChan:
+		return t.granted
+	default:
+	}
+
+	select {
+	case <-t.doneChan:
+		return t.granted
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Cancel withdraws the [Ticket] if it's still pending, removing its N from
+// the [Group.PendingCount].
+// If the [Ticket] was already granted, it frees its N instead, exactly like
+// [Ticket.Release].
+//
+// It blocks until the background reservation, if any, returns.
+// It's safe to call it multiple times, and after [Ticket.Release].
+func (t *Ticket) Cancel() {
+	t.cancelOnce.Do(func() { close(t.cancelChan) })
+	<-t.doneChan
+	t.free()
+}
+
+// Release frees the N of a granted [Ticket].
+// If the [Ticket] is still pending, it's canceled instead, exactly like
+// [Ticket.Cancel].
+//
+// It's safe to call it multiple times, and after [Ticket.Cancel], as the
+// N is freed at most once.
+func (t *Ticket) Release() {
+	t.Cancel()
+}
+
+func (t *Ticket) free() {
+	if !t.granted {
+		return
+	}
+	t.freeOnce.Do(func() { t.g.FreeN(t.n) })
+}
@@ -0,0 +1,79 @@
+package sema_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/asmsh/sema"
+)
+
+func TestGroupSubmit(t *testing.T) {
+	t.Parallel()
+
+	t.Run("a ticket with room is granted right away", func(t *testing.T) {
+		sg := sema.NewGroup(2)
+
+		tk := sg.Submit(2)
+		if !tk.Wait(context.Background()) {
+			t.Fatalf("Ticket should be granted")
+		}
+		if active := sg.ActiveCount(); active != 2 {
+			t.Errorf("Group active count should be 2, got %d", active)
+		}
+
+		tk.Release()
+		tk.Release()
+		if active := sg.ActiveCount(); active != 0 {
+			t.Errorf("Group active count should be 0, got %d", active)
+		}
+	})
+
+	t.Run("a pending ticket is granted after a free", func(t *testing.T) {
+		sg := sema.NewGroup(1)
+		sg.Reserve()
+
+		tk := sg.Submit(1)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		if tk.Wait(ctx) {
+			t.Fatalf("Ticket shouldn't be granted before the free")
+		}
+
+		sg.Free()
+		if !tk.Wait(context.Background()) {
+			t.Fatalf("Ticket should be granted after the free")
+		}
+
+		tk.Release()
+		sg.Wait()
+	})
+
+	t.Run("a canceled ticket is removed from pending", func(t *testing.T) {
+		sg := sema.NewGroup(1)
+		sg.Reserve()
+
+		tk := sg.Submit(1)
+
+		// wait until the background reservation blocks.
+		for sg.PendingCount() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+
+		tk.Cancel()
+		if tk.Wait(context.Background()) {
+			t.Fatalf("Ticket shouldn't be granted after cancel")
+		}
+		if pending := sg.PendingCount(); pending != 0 {
+			t.Errorf("Group pending count should be 0, got %d", pending)
+		}
+
+		// releasing a canceled ticket has no effect.
+		tk.Release()
+		if active := sg.ActiveCount(); active != 1 {
+			t.Errorf("Group active count should be 1, got %d", active)
+		}
+		sg.Free()
+	})
+}
@@ -0,0 +1,69 @@
+// Copyright 2025 Ahmad Sameh(asmsh)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sema
+
+// WaitGrouper is the method set of the [sync.WaitGroup] type, as implemented
+// by the adapter returned by [Group.AsWaitGroup], so the code written against
+// it can use either type.
+type WaitGrouper interface {
+	Add(delta int)
+	Done()
+	Wait()
+}
+
+// AsWaitGroup returns a [WaitGrouper] adapter, backed by the [Group]'s
+// counters, so the code written against the [sync.WaitGroup] method set
+// can use a size-limited [Group] transparently.
+//
+// The adapter's Add reserves a positive delta exactly like [Group.ReserveN],
+// so it blocks while the [Group] has no room for it, and frees a negative
+// delta exactly like [Group.FreeN], while its Done and Wait are exactly like
+// [Group.Free] and [Group.Wait].
+// So it panics on the same misuse that a [sync.WaitGroup] panics on, which is
+// a negative counter.
+// Its Add isn't subject to the gate set via [Group.SetAdmissionGate], like
+// [Group.Reserve], as it can't report a rejection, which would otherwise
+// only show up as a negative counter on the matching Done.
+// Its Add of a delta greater than a non-zero [Group.Size] panics exactly like
+// [Group.ReserveN], so once the [Group] is resized via [Group.Resize], it
+// returns without reserving the delta instead, and the matching Done calls
+// panic on the negative counter.
+func (g *Group) AsWaitGroup() WaitGrouper {
+	return groupWaitGroup{g: g}
+}
+
+type groupWaitGroup struct {
+	g *Group
+}
+
+func (wg groupWaitGroup) Add(delta int) {
+	switch {
+	case delta > 0:
+		outcome := wg.g.reserveN(nil, delta, &reserveOpts{ungated: true})
+		if outcome == Impossible && !wg.g.resized.Load() {
+			wg.g.panic("reserve N exceeds group size")
+		}
+	case delta < 0:
+		wg.g.FreeN(-delta)
+	}
+}
+
+func (wg groupWaitGroup) Done() {
+	wg.g.Free()
+}
+
+func (wg groupWaitGroup) Wait() {
+	wg.g.Wait()
+}